@@ -0,0 +1,119 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterLogFormat(newAccessLogFormat)
+}
+
+// accessLogFormat reads Apache/Nginx access logs in Common or Combined Log
+// Format, including nginx's default that adds X-Forwarded-For and setups
+// that append the request time.
+type accessLogFormat struct {
+	loc *time.Location
+}
+
+func newAccessLogFormat(opts ParseOptions) LogFormat {
+	return accessLogFormat{loc: opts.location()}
+}
+
+const quotedRx = `"((?:[^"\\]|\\.)*)"`
+
+var accessLogRx *regexp.Regexp = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] ` + quotedRx + ` (\d{3}) (\d+|-)(?: ` + quotedRx + ` ` + quotedRx + `)?(?: ` + quotedRx + `)?(?: (\d+(?:\.\d+)?))?\s*$`)
+
+var accessLogTimeLayouts = []string{
+	"02/Jan/2006:15:04:05 -0700",
+	"02/Jan/2006:15:04:05",
+	"02/Jan/2006 15:04:05",
+}
+
+func (f accessLogFormat) Name() string {
+	return "accesslog"
+}
+
+func (f accessLogFormat) Detect(sample []string) float64 {
+	if len(sample) == 0 {
+		return 0
+	}
+	count := 0
+	for _, line := range sample {
+		if accessLogRx.MatchString(line) {
+			count++
+		}
+	}
+	return float64(count) / float64(len(sample))
+}
+
+func (f accessLogFormat) ParseLine(line string) LogLine {
+	ll := LogLine{Raw: line}
+	m := accessLogRx.FindStringSubmatch(line)
+	if m == nil {
+		return ll
+	}
+
+	ll.Fields = map[string]string{}
+	setField := func(key, value string) {
+		if value != "" && value != "-" {
+			ll.Fields[key] = value
+		}
+	}
+
+	setField("remote_addr", m[1])
+	setField("remote_user", m[3])
+	if m[1] != "-" {
+		src := m[1]
+		ll.Src = &src
+	}
+
+	for _, layout := range accessLogTimeLayouts {
+		if t, err := time.ParseInLocation(layout, m[4], f.loc); err == nil {
+			ll.On = &t
+			break
+		}
+	}
+
+	request := unescapeAccessLog(m[5])
+	parts := strings.SplitN(request, " ", 3)
+	if len(parts) == 3 {
+		setField("method", parts[0])
+		setField("path", parts[1])
+		setField("protocol", parts[2])
+	} else {
+		setField("request", request)
+	}
+
+	status := m[6]
+	setField("status", status)
+	setField("bytes", m[7])
+	setField("referer", unescapeAccessLog(m[8]))
+	setField("user_agent", unescapeAccessLog(m[9]))
+	setField("forwarded_for", unescapeAccessLog(m[10]))
+	setField("request_time", m[11])
+
+	level := "INFO"
+	switch status[0] {
+	case '5':
+		level = "ERROR"
+	case '4':
+		level = "WARN"
+	}
+	ll.Level = &level
+	ll.Msg = request + " " + status
+
+	return ll
+}
+
+func (f accessLogFormat) IsContinuation(ll LogLine, open *LogLine) bool {
+	return ll.Fields == nil
+}
+
+func unescapeAccessLog(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(s)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccessLogFormat(testing *testing.T) {
+	data := `127.0.0.1 - frank [17/Apr/2022:11:25:12 +0000] "GET /apache_pb.gif HTTP/1.0" 200 2326
+10.0.0.7 - - [17/Apr/2022:11:25:13 +0200] "POST /login HTTP/1.1" 403 12 "https://example.com/" "Mozilla/5.0 \"X\"" "192.168.0.1" 0.012
+10.0.0.8 - - [17/Apr/2022:11:25:14 +0000] "GET /boom HTTP/1.1" 502 - "-" "curl/7.68.0"
+127.0.0.1 - - [31/Dec/2024 07:58:45] "POST / HTTP/1.1" 404 -
+`
+	lines, state := parseLines(testing, data, ParseOptions{})
+	if state.Format != "accesslog" {
+		testing.Fatalf("Expected accesslog format, got %s", state.Format)
+	}
+	if len(lines) != 4 {
+		testing.Fatalf("Expected 4 lines, got %d", len(lines))
+	}
+
+	ll := lines[0]
+	std := time.Date(2022, 04, 17, 11, 25, 12, 0, time.UTC)
+	if ll.On == nil || !ll.On.Equal(std) {
+		testing.Errorf("Time not parsed: %v", ll.On)
+	}
+	if *ll.Level != "INFO" || *ll.Src != "127.0.0.1" || ll.Fields["remote_user"] != "frank" || ll.Fields["path"] != "/apache_pb.gif" || ll.Fields["bytes"] != "2326" {
+		testing.Errorf("Common log line incorrect: %+v", ll)
+	}
+
+	ll = lines[1]
+	if *ll.Level != "WARN" || ll.Fields["method"] != "POST" || ll.Fields["referer"] != "https://example.com/" ||
+		ll.Fields["user_agent"] != `Mozilla/5.0 "X"` || ll.Fields["forwarded_for"] != "192.168.0.1" || ll.Fields["request_time"] != "0.012" {
+		testing.Errorf("Combined log line incorrect: %+v", ll)
+	}
+
+	ll = lines[2]
+	if *ll.Level != "ERROR" || ll.Fields["bytes"] != "" || ll.Fields["referer"] != "" || ll.Fields["user_agent"] != "curl/7.68.0" {
+		testing.Errorf("Server error line incorrect: %+v", ll)
+	}
+
+	if lines[3].On == nil || *lines[3].Level != "WARN" {
+		testing.Errorf("Python style line incorrect: %+v", lines[3])
+	}
+}
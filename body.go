@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// payloadBody is a JSON or XML payload that spans lines and is still
+// open in the latest entry. Its lines are buffered into the entry's Msg
+// until it closes.
+type payloadBody struct {
+	XML   bool `json:"xml"`
+	Start int  `json:"start"` // where the payload starts in Msg
+	Depth int  `json:"depth"`
+	Lines int  `json:"lines"`
+}
+
+// maxBodyLines stops a payload that never closes from swallowing the rest
+// of the log.
+const maxBodyLines = 1000
+
+var xmlTagRx *regexp.Regexp = regexp.MustCompile(`<(/?)[A-Za-z_][\w:.-]*(?:\s[^<>]*?)?(/?)>`)
+var xmlStartRx *regexp.Regexp = regexp.MustCompile(`<(\?xml|[A-Za-z_][\w:.-]*[\s>])`)
+
+// openBody checks if the line just added to the open entry starts a
+// payload that is not closed on the same line.
+func (p *logParser) openBody(line string) {
+	ll := p.open
+	if ll == nil || len(ll.Stack) > 0 || ll.JSON != nil {
+		return
+	}
+	last := ll.Msg[strings.LastIndex(ll.Msg, "\n")+1:]
+	if last == "" || !strings.HasSuffix(line, last) {
+		return
+	}
+	offset := len(ll.Msg) - len(last)
+	trimmed := strings.TrimSpace(last)
+
+	if strings.HasSuffix(trimmed, "{") || strings.HasSuffix(trimmed, "[") {
+		for i := 0; i < len(last); i++ {
+			if last[i] != '{' && last[i] != '[' {
+				continue
+			}
+			depth := jsonDepth(last[i:])
+			if depth > 0 {
+				p.body = &payloadBody{Start: offset + i, Depth: depth, Lines: 1}
+				return
+			}
+			if end := balancedEnd(last, i); end > 0 {
+				i = end - 1
+			}
+		}
+		return
+	}
+
+	if strings.HasSuffix(trimmed, ">") {
+		// the payload must stand apart, not be a generic like List<String>
+		if loc := xmlStartRx.FindStringIndex(last); loc != nil && (loc[0] == 0 || last[loc[0]-1] == ' ' || last[loc[0]-1] == '\t') {
+			depth := xmlDepth(last[loc[0]:])
+			if depth > 0 {
+				p.body = &payloadBody{XML: true, Start: offset + loc[0], Depth: depth, Lines: 1}
+			}
+		}
+	}
+}
+
+// startsEntry checks if a line ends an open payload by starting an entry
+// of its own, so that a payload that never closes does not swallow the
+// entries after it.
+func (p *logParser) startsEntry(line string) bool {
+	if p.bounds.start != nil {
+		return p.bounds.start.MatchString(line)
+	}
+	if line == "" || goDumpStartRx.MatchString(line) {
+		return line != ""
+	}
+	ll := p.format.ParseLine(line)
+	return ll.On != nil || ll.Level != nil
+}
+
+// feedBody adds a line to the open payload, closing it once balanced.
+func (p *logParser) feedBody(line string) {
+	ll := p.open
+	ll.Msg += "\n" + line
+	ll.Raw += "\n" + line
+	p.extend(ll)
+	p.body.Lines++
+	if p.body.XML {
+		p.body.Depth += xmlDepth(line)
+	} else {
+		p.body.Depth += jsonDepth(line)
+	}
+
+	if p.body.Depth <= 0 {
+		closeBody(ll, p.body)
+		p.body = nil
+	} else if p.body.Lines >= maxBodyLines {
+		p.body = nil
+	}
+}
+
+// closeBody moves a closed payload from Msg into JSON or XML. A JSON body
+// that does not parse is left as text.
+func closeBody(ll *LogLine, body *payloadBody) {
+	text := ll.Msg[body.Start:]
+	var end int
+	if body.XML {
+		locs := xmlTagRx.FindAllStringIndex(text, -1)
+		end = locs[len(locs)-1][1]
+	} else {
+		end = balancedEnd(text, 0)
+		if end < 0 || !json.Valid([]byte(text[:end])) {
+			return
+		}
+	}
+
+	rest := strings.TrimSpace(strings.TrimSpace(ll.Msg[:body.Start]) + " " + strings.TrimSpace(text[end:]))
+	if body.XML {
+		ll.XML = text[:end]
+	} else {
+		ll.JSON = json.RawMessage(text[:end])
+	}
+	ll.Msg = rest
+}
+
+// jsonDepth is how many more brackets the line opens than it closes,
+// ignoring those in strings.
+func jsonDepth(line string) int {
+	depth := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		case '"':
+			end := stringEnd(line, i)
+			if end < 0 {
+				return depth
+			}
+			i = end - 1
+		}
+	}
+	return depth
+}
+
+// xmlDepth is how many more elements the line opens than it closes.
+func xmlDepth(line string) int {
+	depth := 0
+	for _, m := range xmlTagRx.FindAllStringSubmatch(line, -1) {
+		if m[1] == "/" {
+			depth--
+		} else if m[2] != "/" {
+			depth++
+		}
+	}
+	return depth
+}
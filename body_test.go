@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMultiLinePayloads(testing *testing.T) {
+	data := `2022-04-17 11:25:12 INFO request body: {
+  "time": "2022-04-17 11:25:12",
+  "items": [
+    {"id": 1},
+    {"id": 2}
+  ]
+} took 12ms
+2022-04-17 11:25:13 INFO soap call
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <m:Price id="1"/>
+  </soap:Body>
+</soap:Envelope>
+2022-04-17 11:25:14 INFO broken {
+  "a": 1,
+2022-04-17 11:25:15 INFO [main] done
+`
+	lines, _ := parseLines(testing, data, ParseOptions{})
+	if len(lines) != 4 {
+		testing.Fatalf("Expected 4 lines, got %d: %+v", len(lines), lines)
+	}
+	if lines[0].Msg != "request body: took 12ms" || !strings.HasPrefix(string(lines[0].JSON), "{\n  \"time\"") || !strings.HasSuffix(string(lines[0].JSON), "]\n}") {
+		testing.Errorf("JSON body not reassembled: %+v", lines[0])
+	}
+	if lines[1].Msg != "soap call" || !strings.HasPrefix(lines[1].XML, "<soap:Envelope") || !strings.HasSuffix(lines[1].XML, "</soap:Envelope>") {
+		testing.Errorf("XML body not reassembled: %+v", lines[1])
+	}
+	if lines[2].JSON != nil || !strings.Contains(lines[2].Msg, `"a": 1,`) || strings.Contains(lines[2].Msg, "done") {
+		testing.Errorf("Unclosed JSON should be left as text: %+v", lines[2])
+	}
+	if lines[3].Msg != "done" {
+		testing.Errorf("New entry should end an unclosed body: %+v", lines[3])
+	}
+
+	half := strings.Index(data, "    {\"id\": 2}")
+	first, state := parseLines(testing, data[:half], ParseOptions{})
+	if state.Body == nil || first[0].JSON != nil {
+		testing.Fatalf("Open body should be kept to resume: %+v", state)
+	}
+	var resumed []LogLine
+	err := ParseLogFrom(strings.NewReader(data[half:]), "test.log", ParseOptions{}, state, func(ll LogLine) {
+		resumed = append(resumed, ll)
+	})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(resumed) != 4 || string(resumed[0].JSON) != string(lines[0].JSON) {
+		testing.Errorf("Resumed body differs: %+v", resumed)
+	}
+}
+
+func TestUnclosedPayloads(testing *testing.T) {
+	for _, first := range []string{"returning List<String>", "starting batch ["} {
+		lines, _ := parseLines(testing, "2022-04-17 11:25:12 INFO "+first+"\n2022-04-17 11:25:13 INFO one\n2022-04-17 11:25:14 INFO two\n", ParseOptions{})
+		if len(lines) != 3 || lines[0].Msg != first {
+			testing.Errorf("Entries swallowed after %q: %+v", first, lines)
+		}
+	}
+	lines, _ := parseLines(testing, "## INFO items: {\n## next\n", ParseOptions{EntryStart: `^## `})
+	if len(lines) != 2 {
+		testing.Errorf("Entry start should end an unclosed body: %+v", lines)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// entryBounds are a site's own rules for where multi-line entries start
+// and end. When set they decide ahead of the format's heuristics.
+type entryBounds struct {
+	start    *regexp.Regexp
+	cont     *regexp.Regexp
+	maxLines int
+}
+
+func compileEntryBounds(opts ParseOptions) (entryBounds, error) {
+	bounds := entryBounds{maxLines: opts.MaxEntryLines}
+	if opts.EntryStart != "" {
+		r, err := regexp.Compile(opts.EntryStart)
+		if err != nil {
+			return bounds, fmt.Errorf("failed to compile entry start: %s", opts.EntryStart)
+		}
+		bounds.start = r
+	}
+	if opts.Continuation != "" {
+		r, err := regexp.Compile(opts.Continuation)
+		if err != nil {
+			return bounds, fmt.Errorf("failed to compile continuation: %s", opts.Continuation)
+		}
+		bounds.cont = r
+	}
+	return bounds, nil
+}
+
+// continues decides if line continues the open entry. ok is false when
+// there are no rules for it and the format should decide.
+func (b entryBounds) continues(line string) (continues bool, ok bool) {
+	if b.cont != nil && b.cont.MatchString(line) {
+		return true, true
+	}
+	if b.start != nil {
+		return !b.start.MatchString(line), true
+	}
+	if b.cont != nil {
+		return false, true
+	}
+	return false, false
+}
+
+// full reports if the entry has reached the most lines allowed.
+func (b entryBounds) full(ll *LogLine) bool {
+	if b.maxLines <= 0 {
+		return false
+	}
+	return strings.Count(ll.Raw, "\n")+1+len(ll.Stack) >= b.maxLines
+}
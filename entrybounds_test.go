@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEntryBounds(testing *testing.T) {
+	data := `2022-04-17 11:25:12 INFO request
+2022-04-17 11:25:12 INFO   continued with a timestamp
+ new entry starting with a space
+2022-04-17 11:25:13 INFO next
+`
+	opts := ParseOptions{EntryStart: `^(\S+ \S+ INFO request| new| \S+ \S+ INFO next)`, Continuation: `^\S+ \S+ INFO   `}
+	lines, _ := parseLines(testing, data, opts)
+	if len(lines) != 2 {
+		testing.Fatalf("Expected 2 lines, got %d: %+v", len(lines), lines)
+	}
+
+	opts = ParseOptions{EntryStart: `^\S+ \S+ INFO (request|next)|^ new`}
+	lines, _ = parseLines(testing, data, opts)
+	if len(lines) != 3 || lines[0].Msg != "request\n2022-04-17 11:25:12 INFO   continued with a timestamp" || lines[1].Msg != "new entry starting with a space" {
+		testing.Errorf("Entry start not applied: %+v", lines)
+	}
+
+	data = "2022-04-17 11:25:12 ERROR failed\n\tat a.b.C.d(C.java:1)\n\tat a.b.C.e(C.java:2)\n\tat a.b.C.f(C.java:3)\n"
+	lines, _ = parseLines(testing, data, ParseOptions{MaxEntryLines: 3})
+	if len(lines) != 2 || len(lines[0].Stack) != 2 || len(lines[1].Stack) != 1 {
+		testing.Errorf("Max entry lines not applied: %+v", lines)
+	}
+
+	if _, err := compileEntryBounds(ParseOptions{EntryStart: "("}); err == nil {
+		testing.Error("Bad entry start should not compile")
+	}
+}
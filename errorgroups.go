@@ -0,0 +1,238 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorGroup is every occurrence of one error, as told apart by its
+// fingerprint.
+type ErrorGroup struct {
+	Fingerprint string     `json:"fingerprint"`
+	Type        string     `json:"type"`
+	Msg         string     `json:"msg"`
+	Status      string     `json:"status"` // "", "known" or "ignored"
+	Count       int        `json:"count"`
+	First       *time.Time `json:"first"`
+	Last        *time.Time `json:"last"`
+	Sources     []string   `json:"sources"`
+	Files       []string   `json:"files"`
+	Stack       []string   `json:"stack"`
+}
+
+var errorStatuses = []string{"", "known", "ignored"}
+
+// fingerprintFrames is how many of the top frames go into a fingerprint.
+const fingerprintFrames = 3
+
+var exceptionRx *regexp.Regexp = regexp.MustCompile(`(?:^|\s)((?:[\w$]+\.)*[\w$]*(?:Exception|Error|Throwable|Fault)\b)`)
+var generatedFuncRx *regexp.Regexp = regexp.MustCompile(`\$\$Lambda\$[\w/$]*|\$\$\w+\$\$\w+|lambda\$\w+\$\d+|\$\d+|\.func\d+(\.\d+)*`)
+var frameRxx = []*regexp.Regexp{javaFrameRx, nodeFrameRx, dotnetFrameRx, pythonFrameRx, goFileRx}
+
+var errorGroupsMu sync.Mutex
+
+// fingerprint identifies an error entry by its exception type and top app
+// frames, leaving out line numbers and generated names so it survives new
+// builds. Entries with neither a stack nor an error level get none, even
+// if their message names an exception.
+func fingerprint(ll *LogLine) string {
+	kind := errorType(ll)
+	if kind == "" {
+		return ""
+	}
+
+	var frames []StackFrame
+	for _, frame := range ll.Frames {
+		if frame.IsApp {
+			frames = append(frames, frame)
+		}
+	}
+	if len(frames) == 0 {
+		frames = ll.Frames
+	}
+	if len(frames) > fingerprintFrames {
+		frames = frames[:fingerprintFrames]
+	}
+
+	h := sha1.New()
+	h.Write([]byte(kind))
+	for _, frame := range frames {
+		fmt.Fprintf(h, "\n%s", generatedFuncRx.ReplaceAllString(frame.Func, ""))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// errorType is the exception type of the entry, the panic of a Go dump or,
+// for error entries without either, the message with its variable parts
+// masked.
+func errorType(ll *LogLine) string {
+	if len(ll.Stack) == 0 && ll.Severity < SeverityError {
+		return ""
+	}
+	if goDumpStartRx.MatchString(ll.Raw) {
+		return strings.Join(templateTokens(ll.Raw), " ")
+	}
+	for _, line := range ll.Stack {
+		if isFrameLine(line) {
+			continue
+		}
+		if m := exceptionRx.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	if m := exceptionRx.FindStringSubmatch(ll.Msg); m != nil {
+		return m[1]
+	}
+	msg, _, _ := strings.Cut(ll.Msg, "\n")
+	return strings.Join(templateTokens(msg), " ")
+}
+
+func isFrameLine(line string) bool {
+	for _, rx := range frameRxx {
+		if rx.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+type errorStats struct {
+	Count   int        `json:"count"`
+	First   *time.Time `json:"first"`
+	Last    *time.Time `json:"last"`
+	Sources []string   `json:"sources"`
+}
+
+type storedErrorGroup struct {
+	Type   string                 `json:"type"`
+	Msg    string                 `json:"msg"`
+	Status string                 `json:"status"`
+	Stack  []string               `json:"stack"`
+	Files  map[string]*errorStats `json:"files"`
+}
+
+// errorGroups are the error groups of a site, kept in elkdata with their
+// status so that it stays across refreshes.
+type errorGroups struct {
+	Groups map[string]*storedErrorGroup `json:"groups"`
+}
+
+func loadErrorGroups(path string) *errorGroups {
+	groups := &errorGroups{}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		json.Unmarshal(data, groups)
+	}
+	if groups.Groups == nil {
+		groups.Groups = map[string]*storedErrorGroup{}
+	}
+	return groups
+}
+
+func (g *errorGroups) save(path string) error {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (g *errorGroups) add(file string, ll *LogLine) {
+	if ll.Fingerprint == "" {
+		return
+	}
+	group := g.Groups[ll.Fingerprint]
+	if group == nil {
+		group = &storedErrorGroup{Type: errorType(ll), Files: map[string]*errorStats{}}
+		g.Groups[ll.Fingerprint] = group
+	}
+	if len(group.Files) == 0 {
+		group.Msg = ll.Msg
+		group.Stack = ll.Stack
+	}
+
+	stats := group.Files[file]
+	if stats == nil {
+		stats = &errorStats{}
+		group.Files[file] = stats
+	}
+	stats.Count++
+	if ll.On != nil && !ll.Uptime {
+		if stats.First == nil || ll.On.Before(*stats.First) {
+			stats.First = ll.On
+		}
+		if stats.Last == nil || ll.On.After(*stats.Last) {
+			stats.Last = ll.On
+		}
+	}
+	if ll.Src != nil && !contains(stats.Sources, *ll.Src) {
+		stats.Sources = append(stats.Sources, *ll.Src)
+	}
+}
+
+// reset forgets the occurrences in file, before it is parsed again in
+// full. Groups keep their status even when they have no occurrences left.
+func (g *errorGroups) reset(file string) {
+	for _, group := range g.Groups {
+		delete(group.Files, file)
+	}
+}
+
+func (g *errorGroups) setStatus(fingerprint string, status string) error {
+	if !contains(errorStatuses, status) {
+		return fmt.Errorf("invalid error group status %q", status)
+	}
+	group := g.Groups[fingerprint]
+	if group == nil {
+		return fmt.Errorf("no error group %s", fingerprint)
+	}
+	group.Status = status
+	return nil
+}
+
+// list returns the groups seen in file, or in any file if file is empty,
+// most frequent first.
+func (g *errorGroups) list(file string) []ErrorGroup {
+	groups := []ErrorGroup{}
+	for fp, stored := range g.Groups {
+		group := ErrorGroup{Fingerprint: fp, Type: stored.Type, Msg: stored.Msg, Status: stored.Status, Stack: stored.Stack, Sources: []string{}, Files: []string{}}
+		for name, stats := range stored.Files {
+			if file != "" && name != file {
+				continue
+			}
+			group.Count += stats.Count
+			group.Files = append(group.Files, name)
+			if stats.First != nil && (group.First == nil || stats.First.Before(*group.First)) {
+				group.First = stats.First
+			}
+			if stats.Last != nil && (group.Last == nil || stats.Last.After(*group.Last)) {
+				group.Last = stats.Last
+			}
+			for _, src := range stats.Sources {
+				if !contains(group.Sources, src) {
+					group.Sources = append(group.Sources, src)
+				}
+			}
+		}
+		if group.Count > 0 {
+			sort.Strings(group.Files)
+			sort.Strings(group.Sources)
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Fingerprint < groups[j].Fingerprint
+	})
+	return groups
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestErrorGroups(testing *testing.T) {
+	data := `2022-04-17 11:25:12 ERROR [main] com.acme.Api - request failed
+java.lang.NullPointerException: name is null
+	at com.acme.Api.lambda$handle$3(Api.java:10)
+	at com.acme.Api.handle(Api.java:12)
+	at java.lang.Thread.run(Thread.java:750)
+2022-04-17 11:25:13 INFO [main] com.acme.Api - request served
+2022-04-17 11:25:14 ERROR [main] com.acme.Api - request failed again
+java.lang.NullPointerException: id is null
+	at com.acme.Api.lambda$handle$7(Api.java:11)
+	at com.acme.Api.handle(Api.java:14)
+	at java.lang.Thread.run(Thread.java:750)
+2022-04-17 11:25:15 ERROR [main] com.acme.Db - connection 12 lost
+2022-04-17 11:25:16 ERROR [main] com.acme.Db - connection 13 lost
+`
+	lines, _ := parseLines(testing, data, ParseOptions{AppPackages: []string{"com.acme"}})
+	if len(lines) != 5 {
+		testing.Fatalf("Expected 5 lines, got %d: %+v", len(lines), lines)
+	}
+	if lines[0].Fingerprint == "" || lines[0].Fingerprint != lines[2].Fingerprint {
+		testing.Errorf("Same error should have the same fingerprint: %+v", lines)
+	}
+	if lines[1].Fingerprint != "" {
+		testing.Errorf("Info lines should not be fingerprinted: %+v", lines[1])
+	}
+	if lines[3].Fingerprint == "" || lines[3].Fingerprint != lines[4].Fingerprint || lines[3].Fingerprint == lines[0].Fingerprint {
+		testing.Errorf("Errors without stack should be fingerprinted by message: %+v", lines)
+	}
+
+	quiet, _ := parseLines(testing, "2022-04-17 11:25:12 INFO No Error found in batch 12\n2022-04-17 11:25:13 DEBUG retrying after TimeoutException\n", ParseOptions{})
+	for _, ll := range quiet {
+		if ll.Fingerprint != "" {
+			testing.Errorf("Lines below error level without a stack should not be fingerprinted: %+v", ll)
+		}
+	}
+
+	groups := loadErrorGroups(filepath.Join(testing.TempDir(), "missing.json"))
+	for i := range lines {
+		groups.add("a.log", &lines[i])
+	}
+	lines[0].Src = nil
+	groups.add("b.log", &lines[0])
+	list := groups.list("")
+	if len(list) != 2 || list[0].Type != "java.lang.NullPointerException" || list[0].Count != 3 || len(list[0].Files) != 2 || len(list[0].Stack) != 4 {
+		testing.Fatalf("Bad error groups: %+v", list)
+	}
+	if list[0].First.Second() != 12 || list[0].Last.Second() != 14 || len(list[0].Sources) != 1 {
+		testing.Errorf("Bad error group stats: %+v", list[0])
+	}
+	if len(groups.list("b.log")) != 1 {
+		testing.Errorf("Groups should be filtered by file: %+v", groups.list("b.log"))
+	}
+
+	path := filepath.Join(testing.TempDir(), "errors.json")
+	if err := groups.setStatus(list[0].Fingerprint, "ignored"); err != nil {
+		testing.Fatal(err)
+	}
+	if err := groups.setStatus(list[0].Fingerprint, "gone"); err == nil {
+		testing.Error("Unknown status should be rejected")
+	}
+	if err := groups.save(path); err != nil {
+		testing.Fatal(err)
+	}
+	groups = loadErrorGroups(path)
+	groups.reset("a.log")
+	groups.reset("b.log")
+	groups.add("a.log", &lines[2])
+	list = groups.list("")
+	if len(list) != 1 || list[0].Status != "ignored" || list[0].Count != 1 || list[0].Msg != lines[2].Msg {
+		testing.Errorf("Status should stay across refreshes: %+v", list)
+	}
+}
@@ -6,6 +6,10 @@ export function DeleteFTPConfig(arg1:string):Promise<void>;
 
 export function DownloadLog(arg1:main.SiteInfo,arg2:main.FTPEntry):Promise<main.Log>;
 
+export function DownloadLogUpdate(arg1:main.SiteInfo,arg2:main.FTPEntry,arg3:number):Promise<main.LogUpdate>;
+
+export function ErrorGroups(arg1:string,arg2:string):Promise<Array<main.ErrorGroup>>;
+
 export function FetchLocalLog(arg1:string,arg2:string):Promise<main.Log>;
 
 export function GetFTPConfig(arg1:string):Promise<main.FTPConfig>;
@@ -14,6 +18,8 @@ export function GetFileInfos(arg1:main.FTPConfig):Promise<main.SiteInfo>;
 
 export function GetLocalFileInfos(arg1:main.FTPConfig):Promise<main.SiteInfo>;
 
+export function GetTemplates(arg1:string,arg2:string):Promise<Array<main.Template>>;
+
 export function ListFTPConfigs():Promise<Array<string>>;
 
 export function LogError(arg1:string):Promise<void>;
@@ -22,6 +28,12 @@ export function LogInfo(arg1:string):Promise<void>;
 
 export function LogWarning(arg1:string):Promise<void>;
 
+export function PreviewTransformers(arg1:main.FTPConfig,arg2:Array<string>):Promise<Array<main.TransformerPreview>>;
+
 export function ProcessFile(arg1:string,arg2:Array<number>):Promise<string>;
 
+export function ReadLogBytes(arg1:string,arg2:string,arg3:number,arg4:number):Promise<string>;
+
 export function SaveFTPConfig(arg1:main.FTPConfig):Promise<void>;
+
+export function SetErrorGroupStatus(arg1:string,arg2:string,arg3:string):Promise<void>;
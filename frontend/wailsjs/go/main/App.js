@@ -10,6 +10,14 @@ export function DownloadLog(arg1, arg2) {
   return window['go']['main']['App']['DownloadLog'](arg1, arg2);
 }
 
+export function DownloadLogUpdate(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DownloadLogUpdate'](arg1, arg2, arg3);
+}
+
+export function ErrorGroups(arg1, arg2) {
+  return window['go']['main']['App']['ErrorGroups'](arg1, arg2);
+}
+
 export function FetchLocalLog(arg1, arg2) {
   return window['go']['main']['App']['FetchLocalLog'](arg1, arg2);
 }
@@ -26,6 +34,10 @@ export function GetLocalFileInfos(arg1) {
   return window['go']['main']['App']['GetLocalFileInfos'](arg1);
 }
 
+export function GetTemplates(arg1, arg2) {
+  return window['go']['main']['App']['GetTemplates'](arg1, arg2);
+}
+
 export function ListFTPConfigs() {
   return window['go']['main']['App']['ListFTPConfigs']();
 }
@@ -42,10 +54,22 @@ export function LogWarning(arg1) {
   return window['go']['main']['App']['LogWarning'](arg1);
 }
 
+export function PreviewTransformers(arg1, arg2) {
+  return window['go']['main']['App']['PreviewTransformers'](arg1, arg2);
+}
+
 export function ProcessFile(arg1, arg2) {
   return window['go']['main']['App']['ProcessFile'](arg1, arg2);
 }
 
+export function ReadLogBytes(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ReadLogBytes'](arg1, arg2, arg3, arg4);
+}
+
 export function SaveFTPConfig(arg1) {
   return window['go']['main']['App']['SaveFTPConfig'](arg1);
 }
+
+export function SetErrorGroupStatus(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetErrorGroupStatus'](arg1, arg2, arg3);
+}
@@ -1,10 +1,111 @@
 export namespace main {
 	
+	export class ErrorGroup {
+	    fingerprint: string;
+	    type: string;
+	    msg: string;
+	    status: string;
+	    count: number;
+	    // Go type: time
+	    first?: any;
+	    // Go type: time
+	    last?: any;
+	    sources: string[];
+	    files: string[];
+	    stack: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ErrorGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fingerprint = source["fingerprint"];
+	        this.type = source["type"];
+	        this.msg = source["msg"];
+	        this.status = source["status"];
+	        this.count = source["count"];
+	        this.first = this.convertValues(source["first"], null);
+	        this.last = this.convertValues(source["last"], null);
+	        this.sources = source["sources"];
+	        this.files = source["files"];
+	        this.stack = source["stack"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Redaction {
+	    enabled: boolean;
+	    ips: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Redaction(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.ips = source["ips"];
+	    }
+	}
+	export class TimeLayout {
+	    layout: string;
+	    tokens: number;
+	    sample: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimeLayout(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.layout = source["layout"];
+	        this.tokens = source["tokens"];
+	        this.sample = source["sample"];
+	    }
+	}
+	export class JSONKeys {
+	    time: string[];
+	    level: string[];
+	    msg: string[];
+	    src: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new JSONKeys(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = source["time"];
+	        this.level = source["level"];
+	        this.msg = source["msg"];
+	        this.src = source["src"];
+	    }
+	}
 	export class LogTransform {
 	    filenames: string;
 	    match: string;
 	    find: string;
 	    replace: string;
+	    action: string;
+	    field: string;
+	    value: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new LogTransform(source);
@@ -16,6 +117,9 @@ export namespace main {
 	        this.match = source["match"];
 	        this.find = source["find"];
 	        this.replace = source["replace"];
+	        this.action = source["action"];
+	        this.field = source["field"];
+	        this.value = source["value"];
 	    }
 	}
 	export class FTPConfig {
@@ -24,6 +128,18 @@ export namespace main {
 	    user: string;
 	    password: string;
 	    transformers: LogTransform[];
+	    jsonKeys: JSONKeys;
+	    timezone: string;
+	    timeLayouts: TimeLayout[];
+	    levelAliases: {[key: string]: string};
+	    appPackages: string[];
+	    entryStart: string;
+	    continuation: string;
+	    maxEntryLines: number;
+	    redaction: Redaction;
+	    format: string;
+	    grok: string;
+	    grokPatterns: {[key: string]: string};
 	
 	    static createFrom(source: any = {}) {
 	        return new FTPConfig(source);
@@ -36,6 +152,18 @@ export namespace main {
 	        this.user = source["user"];
 	        this.password = source["password"];
 	        this.transformers = this.convertValues(source["transformers"], LogTransform);
+	        this.jsonKeys = this.convertValues(source["jsonKeys"], JSONKeys);
+	        this.timezone = source["timezone"];
+	        this.timeLayouts = this.convertValues(source["timeLayouts"], TimeLayout);
+	        this.levelAliases = source["levelAliases"];
+	        this.appPackages = source["appPackages"];
+	        this.entryStart = source["entryStart"];
+	        this.continuation = source["continuation"];
+	        this.maxEntryLines = source["maxEntryLines"];
+	        this.redaction = this.convertValues(source["redaction"], Redaction);
+	        this.format = source["format"];
+	        this.grok = source["grok"];
+	        this.grokPatterns = source["grokPatterns"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -72,15 +200,111 @@ export namespace main {
 	        this.time = source["time"];
 	    }
 	}
+	export class StackFrame {
+	    func: string;
+	    file: string;
+	    line: number;
+	    module: string;
+	    caused: boolean;
+	    app: boolean;
+	    raw: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new StackFrame(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.func = source["func"];
+	        this.file = source["file"];
+	        this.line = source["line"];
+	        this.module = source["module"];
+	        this.caused = source["caused"];
+	        this.app = source["app"];
+	        this.raw = source["raw"];
+	    }
+	}
+	export class GoroutineGroup {
+	    ids: number[];
+	    count: number;
+	    state: string;
+	    wait: string;
+	    frames: StackFrame[];
+	
+	    static createFrom(source: any = {}) {
+	        return new GoroutineGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ids = source["ids"];
+	        this.count = source["count"];
+	        this.state = source["state"];
+	        this.wait = source["wait"];
+	        this.frames = this.convertValues(source["frames"], StackFrame);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Payload {
+	    start: number;
+	    end: number;
+	    json: number[];
+	    encoded: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Payload(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.json = source["json"];
+	        this.encoded = source["encoded"];
+	    }
+	}
 	export class LogLine {
 	    num: number;
 	    level?: string;
 	    // Go type: time
 	    on_str?: any;
+	    uptime: boolean;
+	    severity: number;
+	    level_raw?: string;
 	    src?: string;
 	    msg: string;
 	    json: number[];
+	    xml: string;
+	    payloads: Payload[];
 	    stack: string[];
+	    frames: StackFrame[];
+	    goroutines: GoroutineGroup[];
+	    fields: {[key: string]: string};
+	    tags: string[];
+	    template: number;
+	    start_line: number;
+	    end_line: number;
+	    byte_offset: number;
+	    byte_length: number;
+	    fingerprint: string;
 	    raw: string;
 	
 	    static createFrom(source: any = {}) {
@@ -92,10 +316,25 @@ export namespace main {
 	        this.num = source["num"];
 	        this.level = source["level"];
 	        this.on_str = this.convertValues(source["on_str"], null);
+	        this.uptime = source["uptime"];
+	        this.severity = source["severity"];
+	        this.level_raw = source["level_raw"];
 	        this.src = source["src"];
 	        this.msg = source["msg"];
 	        this.json = source["json"];
+	        this.xml = source["xml"];
+	        this.payloads = this.convertValues(source["payloads"], Payload);
 	        this.stack = source["stack"];
+	        this.frames = this.convertValues(source["frames"], StackFrame);
+	        this.goroutines = this.convertValues(source["goroutines"], GoroutineGroup);
+	        this.fields = source["fields"];
+	        this.tags = source["tags"];
+	        this.template = source["template"];
+	        this.start_line = source["start_line"];
+	        this.end_line = source["end_line"];
+	        this.byte_offset = source["byte_offset"];
+	        this.byte_length = source["byte_length"];
+	        this.fingerprint = source["fingerprint"];
 	        this.raw = source["raw"];
 	    }
 	
@@ -119,7 +358,11 @@ export namespace main {
 	}
 	export class Log {
 	    name: string;
+	    format: string;
+	    timezone: string;
 	    lines: LogLine[];
+	    offset: number;
+	    warnings: string[];
 	
 	    static createFrom(source: any = {}) {
 	        return new Log(source);
@@ -128,7 +371,51 @@ export namespace main {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.name = source["name"];
+	        this.format = source["format"];
+	        this.timezone = source["timezone"];
 	        this.lines = this.convertValues(source["lines"], LogLine);
+	        this.offset = source["offset"];
+	        this.warnings = source["warnings"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class LogUpdate {
+	    name: string;
+	    lines: LogLine[];
+	    offset: number;
+	    reset: boolean;
+	    warnings: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LogUpdate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.lines = this.convertValues(source["lines"], LogLine);
+	        this.offset = source["offset"];
+	        this.reset = source["reset"];
+	        this.warnings = source["warnings"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -187,6 +474,124 @@ export namespace main {
 		    return a;
 		}
 	}
+	
+	export class Template {
+	    id: number;
+	    pattern: string;
+	    count: number;
+	    // Go type: time
+	    first?: any;
+	    // Go type: time
+	    last?: any;
+	    sample: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Template(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.pattern = source["pattern"];
+	        this.count = source["count"];
+	        this.first = this.convertValues(source["first"], null);
+	        this.last = this.convertValues(source["last"], null);
+	        this.sample = source["sample"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class TransformedLine {
+	    line: number;
+	    before: string;
+	    after: string;
+	    dropped: boolean;
+	    entry?: LogLine;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransformedLine(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.line = source["line"];
+	        this.before = source["before"];
+	        this.after = source["after"];
+	        this.dropped = source["dropped"];
+	        this.entry = this.convertValues(source["entry"], LogLine);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TransformerPreview {
+	    rule: number;
+	    error: string;
+	    matched: TransformedLine[];
+	    dropped: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransformerPreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rule = source["rule"];
+	        this.error = source["error"];
+	        this.matched = this.convertValues(source["matched"], TransformedLine);
+	        this.dropped = source["dropped"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 
 }
 
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -229,6 +230,14 @@ func (a *App) GetFileInfos(config FTPConfig) SiteInfo {
 	return site
 }
 
+type LogUpdate struct {
+	Name     string    `json:"name"`
+	Lines    []LogLine `json:"lines"`
+	Offset   int64     `json:"offset"`
+	Reset    bool      `json:"reset"`
+	Warnings []string  `json:"warnings"`
+}
+
 func (a *App) DownloadLog(site SiteInfo, file *FTPEntry) (*Log, error) {
 	var err error
 	defer func() {
@@ -240,11 +249,37 @@ func (a *App) DownloadLog(site SiteInfo, file *FTPEntry) (*Log, error) {
 
 	runtime.LogInfo(a.ctx, fmt.Sprintf("DownloadLog: %s", file.Name))
 
+	localPath, err := a.downloadLog(site, file)
+	if err != nil {
+		return nil, err
+	}
+	return a.parseLog(localPath, parseOptionsFor(site.Config))
+}
+
+func (a *App) DownloadLogUpdate(site SiteInfo, file *FTPEntry, offset int64) (*LogUpdate, error) {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unexpected error occurred while downloading log update: %v", r)
+			runtime.LogError(a.ctx, err.Error())
+		}
+	}()
+
+	runtime.LogInfo(a.ctx, fmt.Sprintf("DownloadLogUpdate: %s from %d", file.Name, offset))
+
+	localPath, err := a.downloadLog(site, file)
+	if err != nil {
+		return nil, err
+	}
+	return a.parseLogUpdate(localPath, parseOptionsFor(site.Config), offset)
+}
+
+func (a *App) downloadLog(site SiteInfo, file *FTPEntry) (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		err = fmt.Errorf("failed to get home directory: %w", err)
 		runtime.LogError(a.ctx, err.Error())
-		return nil, err
+		return "", err
 	}
 
 	appDataPath := filepath.Join(homeDir, "elkdata", site.Name, "logs")
@@ -252,7 +287,7 @@ func (a *App) DownloadLog(site SiteInfo, file *FTPEntry) (*Log, error) {
 	if err != nil {
 		err = fmt.Errorf("failed to create logs directory: %w", err)
 		runtime.LogError(a.ctx, err.Error())
-		return nil, err
+		return "", err
 	}
 
 	localPath := filepath.Join(appDataPath, file.Name)
@@ -268,14 +303,14 @@ func (a *App) DownloadLog(site SiteInfo, file *FTPEntry) (*Log, error) {
 
 	if since.Hours() < 24 && localSize >= file.Size {
 		runtime.LogInfo(a.ctx, fmt.Sprintf("File %s already exists and size fine (%d >= %d). No need to fetch...", file.Name, localSize, file.Size))
-		return a.parseLog(localPath, nil)
+		return localPath, nil
 	}
 
 	conn, err := a.getConnection(site.Config)
 	if err != nil {
 		err = fmt.Errorf("failed to connect to FTP server: %w", err)
 		runtime.LogError(a.ctx, err.Error())
-		return nil, err
+		return "", err
 	}
 	defer conn.Quit()
 
@@ -285,23 +320,23 @@ func (a *App) DownloadLog(site SiteInfo, file *FTPEntry) (*Log, error) {
 
 		localFile, err := os.OpenFile(localPath, os.O_APPEND|os.O_WRONLY, os.ModePerm)
 		if err != nil {
-			return nil, fmt.Errorf("failed to open local file %s: %w", localPath, err)
+			return "", fmt.Errorf("failed to open local file %s: %w", localPath, err)
 		}
 		defer localFile.Close()
 
 		r, err := conn.RetrFrom(file.Name, localSize)
 		if err != nil {
-			return nil, fmt.Errorf("failed to download part for file %s: %w", file.Name, err)
+			return "", fmt.Errorf("failed to download part for file %s: %w", file.Name, err)
 		}
 		defer r.Close()
 
 		_, err = localFile.ReadFrom(r)
 		if err != nil {
-			return nil, fmt.Errorf("failed to append to file %s: %w", localPath, err)
+			return "", fmt.Errorf("failed to append to file %s: %w", localPath, err)
 		}
 
 		runtime.LogInfo(a.ctx, fmt.Sprintf("Completed part download for file %s successfully", file.Name))
-		return a.parseLog(localPath, nil)
+		return localPath, nil
 
 	} else {
 
@@ -310,15 +345,16 @@ func (a *App) DownloadLog(site SiteInfo, file *FTPEntry) (*Log, error) {
 		if err != nil {
 			err = fmt.Errorf("failed to download file %s: %w", file.Name, err)
 			runtime.LogError(a.ctx, err.Error())
-			return nil, err
+			return "", err
 		}
 		defer r.Close()
 
+		os.Remove(parseStatePath(localPath))
 		localFile, err := os.Create(localPath)
 		if err != nil {
 			err = fmt.Errorf("failed to create local file %s: %w", localPath, err)
 			runtime.LogError(a.ctx, err.Error())
-			return nil, err
+			return "", err
 		}
 		defer localFile.Close()
 
@@ -326,11 +362,11 @@ func (a *App) DownloadLog(site SiteInfo, file *FTPEntry) (*Log, error) {
 		if err != nil {
 			err = fmt.Errorf("failed to save file %s: %w", localPath, err)
 			runtime.LogError(a.ctx, err.Error())
-			return nil, err
+			return "", err
 		}
 
 		runtime.LogInfo(a.ctx, fmt.Sprintf("Downloaded file %s successfully", file.Name))
-		return a.parseLog(localPath, nil)
+		return localPath, nil
 	}
 }
 
@@ -350,8 +386,14 @@ func (a *App) FetchLocalLog(sitename string, filename string) (*Log, error) {
 		return nil, err
 	}
 
+	opts := ParseOptions{}
+	config, err := a.GetFTPConfig(sitename)
+	if err == nil {
+		opts = parseOptionsFor(*config)
+	}
+
 	localPath := filepath.Join(homeDir, "elkdata", sitename, "logs", filename)
-	log, err := a.parseLog(localPath, nil)
+	log, err := a.parseLog(localPath, opts)
 	if err != nil {
 		err = fmt.Errorf("failed to get local log data for %s: %w", filename, err)
 		runtime.LogError(a.ctx, err.Error())
@@ -361,13 +403,313 @@ func (a *App) FetchLocalLog(sitename string, filename string) (*Log, error) {
 	return log, nil
 }
 
-func (a *App) parseLog(logfile string, transformers []LogTransform) (*Log, error) {
-	log, err := ParseLog(logfile, transformers)
+// ReadLogBytes returns the original text of a local log at the given byte
+// range, as given by an entry's ByteOffset and ByteLength.
+func (a *App) ReadLogBytes(sitename string, filename string, offset int64, length int64) (string, error) {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unexpected error occurred while reading log bytes: %v", r)
+			runtime.LogError(a.ctx, err.Error())
+		}
+	}()
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		err = fmt.Errorf("failed to get home directory: %w", err)
+		runtime.LogError(a.ctx, err.Error())
+		return "", err
+	}
+
+	localPath := filepath.Join(homeDir, "elkdata", sitename, "logs", filename)
+	data, err := readLogBytes(localPath, offset, length)
+	if err != nil {
+		err = fmt.Errorf("failed to read %d bytes at %d from %s: %w", length, offset, filename, err)
+		runtime.LogError(a.ctx, err.Error())
+		return "", err
+	}
+
+	var redaction Redaction
+	config, err := a.GetFTPConfig(sitename)
+	if err == nil {
+		redaction = config.Redaction
+	}
+	return newRedactor(redaction).text(string(data)), nil
+}
+
+func readLogBytes(logfile string, offset int64, length int64) ([]byte, error) {
+	file, err := os.Open(logfile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || length < 0 || offset > stat.Size() {
+		return nil, fmt.Errorf("invalid range of %d bytes at %d in a file of %d bytes", length, offset, stat.Size())
+	}
+	if length > maxLogLineSize {
+		return nil, fmt.Errorf("cannot read more than %d bytes at once", maxLogLineSize)
+	}
+	// a range past the end gives what there is
+	if offset+length > stat.Size() {
+		length = stat.Size() - offset
+	}
+
+	data := make([]byte, length)
+	n, err := file.ReadAt(data, offset)
+	if err == io.EOF && n == len(data) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (a *App) parseLog(logfile string, opts ParseOptions) (*Log, error) {
+	log, state, err := parseLogFile(logfile, opts, nil)
 	if err != nil {
 		err = fmt.Errorf("failed parsing log %s: %w", logfile, err)
 		runtime.LogError(a.ctx, err.Error())
 	} else {
+		for _, warning := range log.Warnings {
+			runtime.LogWarning(a.ctx, warning)
+		}
+		count, counted := countedEntries(log.Lines, 0, nil, state)
+		a.mineTemplates(logfile, log.Lines, true, count)
+		a.groupErrors(logfile, log.Lines, true, count)
+		state.Counted = counted
+		a.saveParseState(logfile, state)
 		runtime.LogInfo(a.ctx, fmt.Sprintf("returning %d parsed lines from :%s", len(log.Lines), logfile))
 	}
 	return log, err
 }
+
+// parseLogUpdate returns the entries added or changed since offset. If the
+// saved parse state does not line up with offset the whole log is parsed
+// again and the update is marked as a reset.
+func (a *App) parseLogUpdate(logfile string, opts ParseOptions, offset int64) (*LogUpdate, error) {
+	state, err := loadParseState(logfile)
+	if err != nil || state.Offset != offset || state.Options != opts.hash() {
+		runtime.LogInfo(a.ctx, fmt.Sprintf("no parse state at offset %d with the current options for %s, parsing in full", offset, logfile))
+		log, err := a.parseLog(logfile, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &LogUpdate{Name: log.Name, Lines: log.Lines, Offset: log.Offset, Reset: true, Warnings: log.Warnings}, nil
+	}
+
+	stat, err := os.Stat(logfile)
+	if err == nil && stat.Size() == state.Offset {
+		return &LogUpdate{Name: filepath.Base(logfile), Lines: []LogLine{}, Offset: state.Offset, Warnings: transformerWarnings(opts.Transformers)}, nil
+	}
+
+	counted, wasPending := state.Counted, state.Pending
+	log, state, err := parseLogFile(logfile, opts, state)
+	if err != nil {
+		err = fmt.Errorf("failed parsing log update %s: %w", logfile, err)
+		runtime.LogError(a.ctx, err.Error())
+		return nil, err
+	}
+	count, counted := countedEntries(log.Lines, counted, wasPending, state)
+	a.mineTemplates(logfile, log.Lines, false, count)
+	a.groupErrors(logfile, log.Lines, false, count)
+	state.Counted = counted
+	a.saveParseState(logfile, state)
+	runtime.LogInfo(a.ctx, fmt.Sprintf("returning %d updated lines from :%s", len(log.Lines), logfile))
+	return &LogUpdate{Name: log.Name, Lines: log.Lines, Offset: log.Offset, Warnings: log.Warnings}, nil
+}
+
+// countedEntries picks the entries of a parse to add to the templates and
+// error groups, by Num: those after counted and those left pending by the
+// last parse. Entries still pending, or past the state like a partial
+// last line, may yet change so they are left for a later parse. It also
+// returns the highest entry number counted so far.
+func countedEntries(lines []LogLine, counted int, wasPending []LogLine, state *ParseState) (map[int]bool, int) {
+	pending := map[int]bool{}
+	for _, ll := range state.Pending {
+		pending[ll.Num] = true
+	}
+	leftOver := map[int]bool{}
+	for _, ll := range wasPending {
+		leftOver[ll.Num] = true
+	}
+
+	count := map[int]bool{}
+	upTo := counted
+	for _, ll := range lines {
+		if pending[ll.Num] || ll.Num > state.Num || (ll.Num <= counted && !leftOver[ll.Num]) {
+			continue
+		}
+		count[ll.Num] = true
+		if ll.Num > upTo {
+			upTo = ll.Num
+		}
+	}
+	return count, upTo
+}
+
+// mineTemplates sets the template of every line using the site's miner
+// and counts those in count, which the earlier parses have not. A parse
+// from the start (reset) recounts the whole log.
+func (a *App) mineTemplates(logfile string, lines []LogLine, reset bool, count map[int]bool) {
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+
+	path := templatesPath(logfile)
+	file := filepath.Base(logfile)
+	miner := loadTemplateMiner(path)
+	if reset {
+		miner.reset(file)
+	}
+	for i := range lines {
+		miner.mine(file, &lines[i], count[lines[i].Num])
+	}
+	err := miner.save(path)
+	if err != nil {
+		err = fmt.Errorf("failed to save templates: %w", err)
+		runtime.LogError(a.ctx, err.Error())
+	}
+}
+
+// GetTemplates returns the message templates of a log, or of every log of
+// the site if filename is empty, most frequent first.
+func (a *App) GetTemplates(sitename string, filename string) ([]Template, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		err = fmt.Errorf("failed to get home directory: %w", err)
+		runtime.LogError(a.ctx, err.Error())
+		return nil, err
+	}
+
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+
+	miner := loadTemplateMiner(filepath.Join(homeDir, "elkdata", sitename, "templates.json"))
+	return miner.templates(filename), nil
+}
+
+// groupErrors adds the error lines in count to the site's error
+// groups, the same way mineTemplates counts templates.
+func (a *App) groupErrors(logfile string, lines []LogLine, reset bool, count map[int]bool) {
+	errorGroupsMu.Lock()
+	defer errorGroupsMu.Unlock()
+
+	path := errorGroupsPath(filepath.Dir(filepath.Dir(logfile)))
+	file := filepath.Base(logfile)
+	groups := loadErrorGroups(path)
+	if reset {
+		groups.reset(file)
+	}
+	for i := range lines {
+		if count[lines[i].Num] {
+			groups.add(file, &lines[i])
+		}
+	}
+	err := groups.save(path)
+	if err != nil {
+		err = fmt.Errorf("failed to save error groups: %w", err)
+		runtime.LogError(a.ctx, err.Error())
+	}
+}
+
+// ErrorGroups returns the errors of a log, or of every log of the site if
+// file is empty, grouped by fingerprint.
+func (a *App) ErrorGroups(site string, file string) ([]ErrorGroup, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		err = fmt.Errorf("failed to get home directory: %w", err)
+		runtime.LogError(a.ctx, err.Error())
+		return nil, err
+	}
+
+	errorGroupsMu.Lock()
+	defer errorGroupsMu.Unlock()
+
+	groups := loadErrorGroups(errorGroupsPath(filepath.Join(homeDir, "elkdata", site)))
+	return groups.list(file), nil
+}
+
+// SetErrorGroupStatus marks an error group of the site as "known" or
+// "ignored", or clears its status.
+func (a *App) SetErrorGroupStatus(site string, fingerprint string, status string) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		err = fmt.Errorf("failed to get home directory: %w", err)
+		runtime.LogError(a.ctx, err.Error())
+		return err
+	}
+
+	errorGroupsMu.Lock()
+	defer errorGroupsMu.Unlock()
+
+	path := errorGroupsPath(filepath.Join(homeDir, "elkdata", site))
+	groups := loadErrorGroups(path)
+	err = groups.setStatus(fingerprint, status)
+	if err == nil {
+		err = groups.save(path)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to set status of error group %s: %w", fingerprint, err)
+		runtime.LogError(a.ctx, err.Error())
+	}
+	return err
+}
+
+func errorGroupsPath(siteDir string) string {
+	return filepath.Join(siteDir, "errors.json")
+}
+
+func templatesPath(logfile string) string {
+	return filepath.Join(filepath.Dir(filepath.Dir(logfile)), "templates.json")
+}
+
+func parseStatePath(logfile string) string {
+	return logfile + ".state"
+}
+
+func (a *App) saveParseState(logfile string, state *ParseState) {
+	file, err := os.Create(parseStatePath(logfile))
+	if err != nil {
+		err = fmt.Errorf("failed to create parse state file: %w", err)
+		runtime.LogError(a.ctx, err.Error())
+		return
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	err = encoder.Encode(state)
+	if err != nil {
+		err = fmt.Errorf("failed to write parse state: %w", err)
+		runtime.LogError(a.ctx, err.Error())
+	}
+}
+
+func loadParseState(logfile string) (*ParseState, error) {
+	file, err := os.Open(parseStatePath(logfile))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var state ParseState
+	decoder := json.NewDecoder(file)
+	err = decoder.Decode(&state)
+	if err != nil {
+		return nil, err
+	}
+	if state.Num > 0 && len(state.Pending) == 0 {
+		return nil, fmt.Errorf("parse state has no pending lines")
+	}
+	if state.Offset > 0 && state.Line == 0 {
+		return nil, fmt.Errorf("parse state has no line count")
+	}
+	if state.Num > len(state.Pending) && state.Counted == 0 {
+		return nil, fmt.Errorf("parse state has no counted lines")
+	}
+	return &state, nil
+}
@@ -5,19 +5,87 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 type FTPConfig struct {
-	Name         string         `json:"name"`
-	IP           string         `json:"ip"`
-	User         string         `json:"user"`
-	Password     string         `json:"password"`
-	Transformers []LogTransform `json:"transformers"`
+	Name          string            `json:"name"`
+	IP            string            `json:"ip"`
+	User          string            `json:"user"`
+	Password      string            `json:"password"`
+	Transformers  []LogTransform    `json:"transformers"`
+	JSONKeys      JSONKeys          `json:"jsonKeys"`
+	Timezone      string            `json:"timezone"`
+	TimeLayouts   []TimeLayout      `json:"timeLayouts"`
+	LevelAliases  map[string]string `json:"levelAliases"`
+	AppPackages   []string          `json:"appPackages"`
+	EntryStart    string            `json:"entryStart"`
+	Continuation  string            `json:"continuation"`
+	MaxEntryLines int               `json:"maxEntryLines"`
+	Redaction     Redaction         `json:"redaction"`
+	Format        string            `json:"format"`
+	Grok          string            `json:"grok"`
+	GrokPatterns  map[string]string `json:"grokPatterns"`
+}
+
+func parseOptionsFor(config FTPConfig) ParseOptions {
+	opts := ParseOptions{
+		Transformers:  config.Transformers,
+		JSONKeys:      config.JSONKeys,
+		TimeLayouts:   config.TimeLayouts,
+		LevelAliases:  config.LevelAliases,
+		AppPackages:   config.AppPackages,
+		EntryStart:    config.EntryStart,
+		Continuation:  config.Continuation,
+		MaxEntryLines: config.MaxEntryLines,
+		Redaction:     config.Redaction,
+		Format:        config.Format,
+		Grok:          config.Grok,
+		GrokPatterns:  config.GrokPatterns,
+	}
+	if loc, err := time.LoadLocation(config.Timezone); err == nil {
+		opts.Location = loc
+	}
+	return opts
 }
 
 func (a *App) SaveFTPConfig(config FTPConfig) error {
+	_, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		err = fmt.Errorf("invalid timezone %s: %w", config.Timezone, err)
+		runtime.LogError(a.ctx, err.Error())
+		return err
+	}
+	for _, layout := range config.TimeLayouts {
+		err = layout.validate()
+		if err != nil {
+			runtime.LogError(a.ctx, err.Error())
+			return err
+		}
+	}
+	err = validateLevelAliases(config.LevelAliases)
+	if err != nil {
+		runtime.LogError(a.ctx, err.Error())
+		return err
+	}
+	_, err = compileEntryBounds(parseOptionsFor(config))
+	if err != nil {
+		runtime.LogError(a.ctx, err.Error())
+		return err
+	}
+	_, err = compileTransformers(config.Transformers)
+	if err != nil {
+		runtime.LogError(a.ctx, err.Error())
+		return err
+	}
+	err = validateFormat(config)
+	if err != nil {
+		runtime.LogError(a.ctx, err.Error())
+		return err
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		err = fmt.Errorf("failed to get home directory: %w", err)
@@ -123,3 +191,18 @@ func (a *App) DeleteFTPConfig(name string) error {
 
 	return nil
 }
+
+func validateFormat(config FTPConfig) error {
+	if config.Grok != "" {
+		_, err := compileGrok(config.Grok, config.GrokPatterns)
+		if err != nil {
+			return err
+		}
+	} else if config.Format == "grok" {
+		return fmt.Errorf("the grok format needs a grok expression")
+	}
+	if config.Format != "" && findLogFormat(config.Format, parseOptionsFor(config)).Name() != config.Format {
+		return fmt.Errorf("unknown log format %s", config.Format)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GoroutineGroup is a set of goroutines from a Go panic or goroutine dump
+// that share the same state and stack, collapsed the way panicparse does.
+type GoroutineGroup struct {
+	IDs    []int        `json:"ids"`
+	Count  int          `json:"count"`
+	State  string       `json:"state"`
+	Wait   string       `json:"wait"`
+	Frames []StackFrame `json:"frames"`
+}
+
+var goDumpStartRx *regexp.Regexp = regexp.MustCompile(`^(panic: |fatal error: |SIGQUIT: |SIGABRT: |goroutine \d+ .*\[.*\]:$)`)
+var goroutineRx *regexp.Regexp = regexp.MustCompile(`^goroutine (\d+)(?: [^\[]*)? \[([^\]]*)\]:$`)
+var goDumpLineRx *regexp.Regexp = regexp.MustCompile(`^(goroutine \d+ |\[signal |\t|\s+panic: |created by |exit status \d+|\.\.\.additional frames elided|PC=|r\w+\s+0x|\w+\s+0x[0-9a-f]+$|\S+\(.*\)$)`)
+var goWaitRx *regexp.Regexp = regexp.MustCompile(`^\d+ minutes?$`)
+
+// isGoDump reports if the entry is a Go panic or goroutine dump, whose
+// following lines all belong to it.
+func isGoDump(ll *LogLine) bool {
+	return goDumpStartRx.MatchString(ll.Raw) || (len(ll.Stack) > 0 && goroutineRx.MatchString(ll.Stack[0]))
+}
+
+// feedGoDump keeps the lines of a Go panic or goroutine dump together.
+// A panic starts an entry of its own while a goroutine header following a
+// log entry becomes its stack.
+func (p *logParser) feedGoDump(line string) bool {
+	if p.open != nil && isGoDump(p.open) && goDumpLineRx.MatchString(line) {
+		p.open.Stack = append(p.open.Stack, line)
+		p.extend(p.open)
+		return true
+	}
+	if !goDumpStartRx.MatchString(line) {
+		return false
+	}
+	if goroutineRx.MatchString(line) && p.open != nil && (p.open.On != nil || p.open.Level != nil) {
+		p.open.Stack = append(p.open.Stack, line)
+		p.extend(p.open)
+		return true
+	}
+
+	ll := p.format.ParseLine(line)
+	ll.Raw = line
+	ll.Msg = line
+	if ll.Level == nil {
+		level := "PANIC"
+		if strings.HasPrefix(line, "fatal error: ") {
+			level = "FATAL"
+		}
+		ll.Level = &level
+	}
+	if goroutineRx.MatchString(line) {
+		ll.Msg = ""
+		ll.Stack = []string{line}
+	}
+	p.start(ll)
+	return true
+}
+
+// groupGoroutines splits a goroutine dump into its goroutines and
+// collapses those with identical stacks.
+func groupGoroutines(stack []string, appPackages []string) []GoroutineGroup {
+	groups := []GoroutineGroup{}
+	index := map[string]int{}
+
+	add := func(id int, status string, lines []string) {
+		parts := strings.Split(status, ", ")
+		state := parts[0]
+		wait := ""
+		for _, part := range parts[1:] {
+			if goWaitRx.MatchString(part) {
+				wait = part
+			}
+		}
+		frames := parseStackFrames(lines, appPackages)
+
+		key := []string{state}
+		for _, frame := range frames {
+			key = append(key, frame.Func+" "+frame.File+":"+strconv.Itoa(frame.Line))
+		}
+		k := strings.Join(key, "\n")
+		if i, ok := index[k]; ok {
+			g := &groups[i]
+			g.IDs = append(g.IDs, id)
+			g.Count++
+			if waitMinutes(wait) > waitMinutes(g.Wait) {
+				g.Wait = wait
+			}
+			return
+		}
+		index[k] = len(groups)
+		groups = append(groups, GoroutineGroup{IDs: []int{id}, Count: 1, State: state, Wait: wait, Frames: frames})
+	}
+
+	var id int
+	var status string
+	var lines []string
+	started := false
+	for _, line := range stack {
+		if m := goroutineRx.FindStringSubmatch(line); m != nil {
+			if started {
+				add(id, status, lines)
+			}
+			id, _ = strconv.Atoi(m[1])
+			status = m[2]
+			lines = nil
+			started = true
+			continue
+		}
+		if started {
+			lines = append(lines, line)
+		}
+	}
+	if started {
+		add(id, status, lines)
+	}
+	return groups
+}
+
+func waitMinutes(wait string) int {
+	n, _ := strconv.Atoi(strings.Fields(wait + " 0")[0])
+	return n
+}
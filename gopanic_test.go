@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGoPanicGrouping(testing *testing.T) {
+	data := `2024-12-30 04:50:41 INFO starting worker
+panic: runtime error: invalid memory address or nil pointer dereference
+[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x48f5a6]
+
+goroutine 1 [running]:
+main.(*Worker).run(0x0)
+	/src/worker.go:42 +0x26
+main.main()
+	/src/main.go:9 +0x25
+
+goroutine 18 [chan receive, 5 minutes]:
+main.listen(0xc000010000)
+	/src/listen.go:12 +0x3a
+created by main.main in goroutine 1
+	/src/main.go:7 +0x1d
+
+goroutine 19 [chan receive, 7 minutes]:
+main.listen(0xc000010008)
+	/src/listen.go:12 +0x3a
+created by main.main in goroutine 1
+	/src/main.go:7 +0x1d
+exit status 2
+2024-12-30 04:50:42 INFO restarted
+`
+	lines, _ := parseLines(testing, data, ParseOptions{})
+	if len(lines) != 3 {
+		testing.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+
+	ll := lines[1]
+	if ll.Level == nil || ll.Severity != SeverityFatal || ll.Msg != "panic: runtime error: invalid memory address or nil pointer dereference" {
+		testing.Errorf("Panic entry incorrect: %+v", ll)
+	}
+	if len(ll.Goroutines) != 2 {
+		testing.Fatalf("Expected 2 goroutine groups, got %d: %+v", len(ll.Goroutines), ll.Goroutines)
+	}
+	g := ll.Goroutines[0]
+	if g.Count != 1 || g.IDs[0] != 1 || g.State != "running" || len(g.Frames) != 2 || g.Frames[0].Func != "main.(*Worker).run" {
+		testing.Errorf("Running goroutine incorrect: %+v", g)
+	}
+	g = ll.Goroutines[1]
+	if g.Count != 2 || g.IDs[0] != 18 || g.IDs[1] != 19 || g.State != "chan receive" || g.Wait != "7 minutes" || len(g.Frames) != 2 {
+		testing.Errorf("Collapsed goroutines incorrect: %+v", g)
+	}
+}
+
+func TestGoroutineDumpInLogEntry(testing *testing.T) {
+	data := `2024-12-30 04:50:41 ERROR handler crashed
+goroutine 7 [running]:
+runtime/debug.Stack()
+	/usr/local/go/src/runtime/debug/stack.go:24 +0x5e
+2024-12-30 04:50:42 INFO next
+`
+	lines, _ := parseLines(testing, data, ParseOptions{})
+	if len(lines) != 2 || len(lines[0].Stack) != 3 || len(lines[0].Goroutines) != 1 {
+		testing.Errorf("Goroutine not attached to log entry: %+v", lines)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGrokPatterns(testing *testing.T) {
+	for name := range grokPatterns {
+		if _, err := compileGrok("%{"+name+"}", nil); err != nil {
+			testing.Errorf("Pattern %s does not compile: %v", name, err)
+		}
+	}
+
+	g, err := compileGrok(`%{COMBINEDAPACHELOG}`, nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	fields := g.match(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`)
+	if fields["clientip"] != "127.0.0.1" || fields["auth"] != "frank" || fields["response"] != "200" || fields["agent"] != `"Mozilla/4.08"` {
+		testing.Errorf("Bad apache captures: %v", fields)
+	}
+
+	custom := map[string]string{"ORDER": `ORD-%{INT:order.id}`, "LOOP": `%{LOOP}`}
+	g, err = compileGrok(`%{ORDER} by %{WORD:user}`, custom)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if fields := g.match("ORD-42 by bob"); fields["order.id"] != "42" || fields["user"] != "bob" {
+		testing.Errorf("Bad custom captures: %v", fields)
+	}
+	if g.match("nothing here") != nil {
+		testing.Error("Non matching line should give no fields")
+	}
+
+	if _, err := compileGrok(`%{NOPE}`, nil); err == nil {
+		testing.Error("Unknown pattern should not compile")
+	}
+	if _, err := compileGrok(`%{LOOP}`, custom); err == nil {
+		testing.Error("Recursive pattern should not compile")
+	}
+}
+
+func TestGrokFormat(testing *testing.T) {
+	data := `2022-04-17 11:25:12,345 [main] ERROR com.acme.Api - request failed
+java.lang.IllegalStateException: bad
+	at com.acme.Api.run(Api.java:10)
+2022-04-17 11:25:13,001 [worker-1] INFO com.acme.Job - done user=bob
+`
+	opts := ParseOptions{
+		Format: "grok",
+		Grok:   `%{TIMESTAMP_ISO8601:ts} \[%{DATA:thread}\] %{LOGLEVEL:level} %{JAVACLASS:src} - %{GREEDYDATA:msg}`,
+	}
+	lines, state := parseLines(testing, data, opts)
+	if state.Format != "grok" || len(lines) != 2 {
+		testing.Fatalf("Expected 2 grok lines, got %s %+v", state.Format, lines)
+	}
+	ll := lines[0]
+	if ll.On == nil || ll.On.Second() != 12 || *ll.Level != "ERROR" || *ll.Src != "com.acme.Api main" || ll.Msg != "request failed" || ll.Fields["thread"] != "main" || len(ll.Stack) != 2 {
+		testing.Errorf("Bad grok entry: %+v", ll)
+	}
+	if lines[1].Severity != SeverityInfo || lines[1].Msg != "done user=bob" {
+		testing.Errorf("Bad grok entry: %+v", lines[1])
+	}
+
+	if err := validateFormat(FTPConfig{Format: "grok"}); err == nil {
+		testing.Error("Grok format without an expression should be rejected")
+	}
+	if err := validateFormat(FTPConfig{Format: "nope"}); err == nil {
+		testing.Error("Unknown format should be rejected")
+	}
+	if err := validateFormat(FTPConfig{Format: "grok", Grok: opts.Grok}); err != nil {
+		testing.Error(err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+func init() {
+	RegisterLogFormat(newGrokFormat)
+}
+
+// grokFormat parses lines with the site's grok expression. The captures
+// named like the JSON keys fill in the LogLine and the rest go to Fields.
+// Lines that do not match continue the entry before them.
+type grokFormat struct {
+	grok *grok
+	keys JSONKeys
+	tp   timeParser
+}
+
+func newGrokFormat(opts ParseOptions) LogFormat {
+	f := grokFormat{keys: opts.JSONKeys.withDefaults(), tp: opts.timeParser()}
+	if opts.Grok != "" {
+		f.grok, _ = compileGrok(opts.Grok, opts.GrokPatterns)
+	}
+	return f
+}
+
+func (f grokFormat) Name() string {
+	return "grok"
+}
+
+func (f grokFormat) Detect(sample []string) float64 {
+	if f.grok == nil || len(sample) == 0 {
+		return 0
+	}
+	count := 0
+	for _, line := range sample {
+		if f.grok.rx.MatchString(line) {
+			count++
+		}
+	}
+	return float64(count) / float64(len(sample))
+}
+
+func (f grokFormat) ParseLine(line string) LogLine {
+	ll := LogLine{Raw: line}
+	if f.grok == nil {
+		return ll
+	}
+	captures := f.grok.match(line)
+	if captures == nil {
+		return ll
+	}
+
+	keys := make([]string, 0, len(captures))
+	for key := range captures {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ll.Fields = map[string]string{}
+	srcs := []string{}
+	for _, key := range keys {
+		value := captures[key]
+		switch {
+		case ll.On == nil && contains(f.keys.Time, key):
+			ll.On = f.tp.parseValue(value)
+			if ll.On == nil {
+				ll.Fields[key] = value
+			}
+		case ll.Level == nil && contains(f.keys.Level, key):
+			level := value
+			ll.Level = &level
+		case contains(f.keys.Msg, key):
+			ll.Msg = value
+		case key == "src" || contains(f.keys.Src, key):
+			srcs = append(srcs, value)
+			if key == "thread" {
+				ll.Fields[key] = value
+			}
+		default:
+			ll.Fields[key] = value
+		}
+	}
+	if len(srcs) > 0 {
+		src := strings.Join(srcs, " ")
+		ll.Src = &src
+	}
+	return ll
+}
+
+func (f grokFormat) IsContinuation(ll LogLine, open *LogLine) bool {
+	return ll.Fields == nil
+}
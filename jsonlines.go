@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// JSONKeys lists the keys a JSON-lines or logfmt log uses for each LogLine
+// field. Any left empty fall back to the usual names.
+type JSONKeys struct {
+	Time  []string `json:"time"`
+	Level []string `json:"level"`
+	Msg   []string `json:"msg"`
+	Src   []string `json:"src"`
+}
+
+var defaultJSONKeys = JSONKeys{
+	Time:  []string{"time", "ts", "@timestamp", "timestamp"},
+	Level: []string{"level", "severity", "lvl", "loglevel"},
+	Msg:   []string{"msg", "message"},
+	Src:   []string{"logger", "logger_name", "caller", "thread", "thread_name"},
+}
+
+func init() {
+	RegisterLogFormat(newJSONLinesFormat)
+}
+
+type jsonLinesFormat struct {
+	keys JSONKeys
+	tp   timeParser
+}
+
+func newJSONLinesFormat(opts ParseOptions) LogFormat {
+	return jsonLinesFormat{keys: opts.JSONKeys.withDefaults(), tp: opts.timeParser()}
+}
+
+func (keys JSONKeys) withDefaults() JSONKeys {
+	if len(keys.Time) == 0 {
+		keys.Time = defaultJSONKeys.Time
+	}
+	if len(keys.Level) == 0 {
+		keys.Level = defaultJSONKeys.Level
+	}
+	if len(keys.Msg) == 0 {
+		keys.Msg = defaultJSONKeys.Msg
+	}
+	if len(keys.Src) == 0 {
+		keys.Src = defaultJSONKeys.Src
+	}
+	return keys
+}
+
+func (f jsonLinesFormat) Name() string {
+	return "jsonlines"
+}
+
+func (f jsonLinesFormat) Detect(sample []string) float64 {
+	if len(sample) == 0 {
+		return 0
+	}
+	count := 0
+	for _, line := range sample {
+		if isJSONObjectLine(line) {
+			count++
+		}
+	}
+	return float64(count) / float64(len(sample))
+}
+
+func isJSONObjectLine(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "{") && json.Valid([]byte(line))
+}
+
+func (f jsonLinesFormat) ParseLine(line string) LogLine {
+	ll := LogLine{Raw: line}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &obj); err != nil {
+		return ll
+	}
+
+	if v, ok := popJSONKey(obj, f.keys.Time); ok {
+		ll.On = f.jsonTime(v)
+	}
+	if v, ok := popJSONKey(obj, f.keys.Level); ok {
+		if level := jsonString(v); level != "" {
+			ll.Level = &level
+		}
+	}
+	if v, ok := popJSONKey(obj, f.keys.Msg); ok {
+		ll.Msg = jsonString(v)
+	}
+	srcs := []string{}
+	for _, key := range f.keys.Src {
+		if v, ok := obj[key]; ok {
+			delete(obj, key)
+			if src := jsonString(v); src != "" {
+				srcs = append(srcs, src)
+			}
+		}
+	}
+	if len(srcs) > 0 {
+		src := strings.Join(srcs, " ")
+		ll.Src = &src
+	}
+
+	if len(obj) > 0 {
+		ll.JSON, _ = json.Marshal(obj)
+	} else {
+		ll.JSON = json.RawMessage("{}")
+	}
+	return ll
+}
+
+func (f jsonLinesFormat) IsContinuation(ll LogLine, open *LogLine) bool {
+	return ll.JSON == nil
+}
+
+func popJSONKey(obj map[string]json.RawMessage, keys []string) (json.RawMessage, bool) {
+	for _, key := range keys {
+		if v, ok := obj[key]; ok {
+			delete(obj, key)
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// jsonString returns string values as is and anything else as its JSON
+// text.
+func jsonString(v json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(v, &s); err == nil {
+		return s
+	}
+	return string(v)
+}
+
+func (f jsonLinesFormat) jsonTime(v json.RawMessage) *time.Time {
+	value := jsonString(v)
+	if t, ok := epochTime(value); ok {
+		return &t
+	}
+	return f.tp.parseValue(value)
+}
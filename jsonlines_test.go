@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseLines(testing *testing.T, data string, opts ParseOptions) ([]LogLine, *ParseState) {
+	lines := []LogLine{}
+	state := &ParseState{}
+	err := ParseLogFrom(strings.NewReader(data), "test.log", opts, state, func(ll LogLine) {
+		lines = append(lines, ll)
+	})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	return lines, state
+}
+
+func TestJSONLinesFormat(testing *testing.T) {
+	data := `{"ts":1713353112.345,"level":"warn","msg":"disk low","logger":"disk","free":12}
+{"@timestamp":"2022-04-17T11:25:12.345Z","severity":"ERROR","message":"write failed","thread":"main"}
+stderr: not json
+{"time":1713353112345,"lvl":"info","msg":"ok"}
+`
+	lines, state := parseLines(testing, data, ParseOptions{})
+	if state.Format != "jsonlines" {
+		testing.Fatalf("Expected jsonlines format, got %s", state.Format)
+	}
+	if len(lines) != 3 {
+		testing.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+
+	std := time.Date(2024, 04, 17, 11, 25, 12, 345*1e6, time.UTC)
+	ll := lines[0]
+	if ll.On == nil || !ll.On.Equal(std) {
+		testing.Errorf("Epoch seconds not parsed: %v", ll.On)
+	}
+	if ll.Level == nil || *ll.Level != "warn" || ll.Msg != "disk low" || ll.Src == nil || *ll.Src != "disk" {
+		testing.Errorf("Fields not mapped: %+v", ll)
+	}
+	var rest map[string]any
+	json.Unmarshal(ll.JSON, &rest)
+	if len(rest) != 1 || rest["free"] != float64(12) {
+		testing.Errorf("Remaining JSON incorrect: %s", ll.JSON)
+	}
+
+	if lines[1].On == nil || lines[1].Msg != "write failed\nstderr: not json" {
+		testing.Errorf("Continuation not grouped: %+v", lines[1])
+	}
+	if lines[2].On == nil || !lines[2].On.Equal(std) {
+		testing.Errorf("Epoch milliseconds not parsed: %v", lines[2].On)
+	}
+}
+
+func TestJSONLinesCustomKeys(testing *testing.T) {
+	data := `{"when":"2022-04-17 11:25:12","sev":"E","text":"custom"}` + "\n"
+	opts := ParseOptions{JSONKeys: JSONKeys{Time: []string{"when"}, Level: []string{"sev"}, Msg: []string{"text"}}}
+	lines, _ := parseLines(testing, data, opts)
+	if len(lines) != 1 || lines[0].On == nil || lines[0].Level == nil || lines[0].Msg != "custom" {
+		testing.Errorf("Custom keys not mapped: %+v", lines)
+	}
+}
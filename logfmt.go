@@ -0,0 +1,158 @@
+package main
+
+import (
+	"strings"
+)
+
+func init() {
+	RegisterLogFormat(newLogfmtFormat)
+}
+
+// logfmtFormat reads `key=value` lines as written by Go and Heroku style
+// loggers. The standard keys fill in the LogLine and the rest go to Fields.
+type logfmtFormat struct {
+	keys JSONKeys
+	tp   timeParser
+}
+
+func newLogfmtFormat(opts ParseOptions) LogFormat {
+	return logfmtFormat{keys: opts.JSONKeys.withDefaults(), tp: opts.timeParser()}
+}
+
+func (f logfmtFormat) Name() string {
+	return "logfmt"
+}
+
+func (f logfmtFormat) Detect(sample []string) float64 {
+	if len(sample) == 0 {
+		return 0
+	}
+	count := 0
+	for _, line := range sample {
+		if pairs, ok := parseLogfmt(line); ok && len(pairs) > 1 {
+			count++
+		}
+	}
+	return float64(count) / float64(len(sample))
+}
+
+func (f logfmtFormat) ParseLine(line string) LogLine {
+	ll := LogLine{Raw: line}
+
+	pairs, ok := parseLogfmt(line)
+	if !ok || len(pairs) < 2 {
+		return ll
+	}
+
+	ll.Fields = map[string]string{}
+	srcs := []string{}
+	for _, pair := range pairs {
+		switch {
+		case ll.On == nil && contains(f.keys.Time, pair.key):
+			ll.On = f.tp.parseValue(pair.value)
+			if ll.On == nil {
+				ll.Fields[pair.key] = pair.value
+			}
+		case ll.Level == nil && contains(f.keys.Level, pair.key):
+			level := pair.value
+			ll.Level = &level
+		case ll.Msg == "" && contains(f.keys.Msg, pair.key):
+			ll.Msg = pair.value
+		case contains(f.keys.Src, pair.key):
+			srcs = append(srcs, pair.value)
+		default:
+			ll.Fields[pair.key] = pair.value
+		}
+	}
+	if len(srcs) > 0 {
+		src := strings.Join(srcs, " ")
+		ll.Src = &src
+	}
+	return ll
+}
+
+func (f logfmtFormat) IsContinuation(ll LogLine, open *LogLine) bool {
+	return ll.Fields == nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type logfmtPair struct {
+	key   string
+	value string
+}
+
+// parseLogfmt splits a line into its key=value pairs, unquoting quoted
+// values. It fails if anything in the line is not a pair.
+func parseLogfmt(line string) ([]logfmtPair, bool) {
+	pairs := []logfmtPair{}
+	i := 0
+	for i < len(line) {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		if i == len(line) {
+			break
+		}
+
+		start := i
+		for i < len(line) && line[i] > ' ' && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		if i == start || i == len(line) || line[i] != '=' {
+			return nil, false
+		}
+		key := line[start:i]
+		i++
+
+		if i < len(line) && line[i] == '"' {
+			var value strings.Builder
+			i++
+			closed := false
+			for i < len(line) {
+				c := line[i]
+				if c == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						value.WriteByte('\n')
+					case 't':
+						value.WriteByte('\t')
+					case 'r':
+						value.WriteByte('\r')
+					default:
+						value.WriteByte(line[i])
+					}
+				} else if c == '"' {
+					closed = true
+					i++
+					break
+				} else {
+					value.WriteByte(c)
+				}
+				i++
+			}
+			if !closed || (i < len(line) && line[i] != ' ' && line[i] != '\t') {
+				return nil, false
+			}
+			pairs = append(pairs, logfmtPair{key, value.String()})
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				if line[i] == '"' {
+					return nil, false
+				}
+				i++
+			}
+			pairs = append(pairs, logfmtPair{key, line[start:i]})
+		}
+	}
+	return pairs, true
+}
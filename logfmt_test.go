@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLogfmt(testing *testing.T) {
+	pairs, ok := parseLogfmt(`ts=2022-04-17T11:25:12Z level=warn msg="disk \"sda\" low\ttoday" empty= user=42`)
+	if !ok {
+		testing.Fatal("Failed to parse logfmt line")
+	}
+	expected := []logfmtPair{
+		{"ts", "2022-04-17T11:25:12Z"},
+		{"level", "warn"},
+		{"msg", "disk \"sda\" low\ttoday"},
+		{"empty", ""},
+		{"user", "42"},
+	}
+	if len(pairs) != len(expected) {
+		testing.Fatalf("Expected %d pairs, got %d", len(expected), len(pairs))
+	}
+	for i, pair := range pairs {
+		if pair != expected[i] {
+			testing.Errorf("Pair %d incorrect: %v", i, pair)
+		}
+	}
+
+	for _, line := range []string{
+		`2022-04-17 11:25:12 INFO not logfmt`,
+		`msg="unterminated`,
+		`a=1 b`,
+	} {
+		if _, ok := parseLogfmt(line); ok {
+			testing.Errorf("Should not parse as logfmt: %s", line)
+		}
+	}
+}
+
+func TestLogfmtFormat(testing *testing.T) {
+	data := `ts=2022-04-17T11:25:12.345Z level=warn msg="slow request" caller=api.go:12 user=42 dur=13ms
+time="2022-04-17 11:25:13" level=error msg="request failed"
+	at handler
+`
+	lines, state := parseLines(testing, data, ParseOptions{})
+	if state.Format != "logfmt" {
+		testing.Fatalf("Expected logfmt format, got %s", state.Format)
+	}
+	if len(lines) != 2 {
+		testing.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+
+	ll := lines[0]
+	std := time.Date(2022, 04, 17, 11, 25, 12, 345*1e6, time.UTC)
+	if ll.On == nil || !ll.On.Equal(std) {
+		testing.Errorf("Time not parsed: %v", ll.On)
+	}
+	if ll.Level == nil || *ll.Level != "warn" || ll.Msg != "slow request" || ll.Src == nil || *ll.Src != "api.go:12" {
+		testing.Errorf("Fields not mapped: %+v", ll)
+	}
+	if len(ll.Fields) != 2 || ll.Fields["user"] != "42" || ll.Fields["dur"] != "13ms" {
+		testing.Errorf("Extra fields incorrect: %v", ll.Fields)
+	}
+	if lines[1].On == nil || lines[1].Msg != "request failed" || len(lines[1].Stack) != 1 {
+		testing.Errorf("Second line incorrect: %+v", lines[1])
+	}
+}
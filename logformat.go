@@ -0,0 +1,83 @@
+package main
+
+// LogFormat knows how to recognise and parse one kind of log. Detect
+// scores a sample of lines from 0 to 1 and the format with the best score
+// is used for the whole log.
+type LogFormat interface {
+	Name() string
+	Detect(sample []string) float64
+	ParseLine(line string) LogLine
+	// IsContinuation reports if ll belongs to the open entry instead of
+	// starting a new one. open is nil at the start of the log.
+	IsContinuation(ll LogLine, open *LogLine) bool
+}
+
+const formatSampleSize = 300
+
+// LogFormatFactory sets up a format for the site options of one parse.
+type LogFormatFactory func(opts ParseOptions) LogFormat
+
+var logFormats []LogFormatFactory
+
+func RegisterLogFormat(factory LogFormatFactory) {
+	logFormats = append(logFormats, factory)
+}
+
+func init() {
+	RegisterLogFormat(newGenericFormat)
+}
+
+func findLogFormat(name string, opts ParseOptions) LogFormat {
+	for _, factory := range logFormats {
+		format := factory(opts)
+		if format.Name() == name {
+			return format
+		}
+	}
+	return newGenericFormat(opts)
+}
+
+func detectLogFormat(sample []string, opts ParseOptions) LogFormat {
+	best := newGenericFormat(opts)
+	bestScore := best.Detect(sample)
+	for _, factory := range logFormats {
+		format := factory(opts)
+		score := format.Detect(sample)
+		if score > bestScore {
+			best = format
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// genericFormat is the heuristic parser that pulls a datetime, level and
+// sources off the front of any line. Other formats have to recognise more
+// than half the sample to be picked over it.
+type genericFormat struct {
+	tp timeParser
+	lm levelMatcher
+}
+
+func newGenericFormat(opts ParseOptions) LogFormat {
+	return genericFormat{tp: opts.timeParser(), lm: newLevelMatcher(opts.LevelAliases)}
+}
+
+func (genericFormat) Name() string {
+	return "generic"
+}
+
+func (genericFormat) Detect(sample []string) float64 {
+	return 0.5
+}
+
+func (f genericFormat) ParseLine(line string) LogLine {
+	return parseLogLine(line, f.tp, f.lm)
+}
+
+func (genericFormat) IsContinuation(ll LogLine, open *LogLine) bool {
+	if ll.Msg == "" && ll.Src == nil && ll.On == nil && ll.Raw != "" {
+		return true
+	}
+	return open != nil && (ll.On == nil && ll.Level == nil) && (open.On != nil || open.Level != nil)
+}
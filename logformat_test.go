@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+type hashFormat struct{}
+
+func (hashFormat) Name() string {
+	return "hash"
+}
+
+func (hashFormat) Detect(sample []string) float64 {
+	count := 0
+	for _, line := range sample {
+		if strings.HasPrefix(line, "##") {
+			count++
+		}
+	}
+	return float64(count) / float64(len(sample)+1)
+}
+
+func (hashFormat) ParseLine(line string) LogLine {
+	return LogLine{Raw: line, Msg: strings.TrimPrefix(line, "##")}
+}
+
+func (hashFormat) IsContinuation(ll LogLine, open *LogLine) bool {
+	return !strings.HasPrefix(ll.Raw, "##")
+}
+
+func TestDetectLogFormat(testing *testing.T) {
+	saved := logFormats
+	defer func() { logFormats = saved }()
+	RegisterLogFormat(func(opts ParseOptions) LogFormat { return hashFormat{} })
+
+	log, err := ParseLog("sample_test.log", ParseOptions{})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if log.Format != "generic" {
+		testing.Errorf("Expected generic format, got %s", log.Format)
+	}
+
+	data := "##one\n##two\ncontinued\n##three\n"
+	lines := []LogLine{}
+	state := &ParseState{}
+	err = ParseLogFrom(strings.NewReader(data), "test.log", ParseOptions{}, state, func(ll LogLine) {
+		lines = append(lines, ll)
+	})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if state.Format != "hash" {
+		testing.Errorf("Expected hash format, got %s", state.Format)
+	}
+	if len(lines) != 3 || lines[1].Msg != "two\ncontinued" {
+		testing.Errorf("Lines not grouped by format: %v", lines)
+	}
+}
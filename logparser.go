@@ -1,37 +1,139 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
 )
 
 type Log struct {
-	Name  string    `json:"name"`
-	Lines []LogLine `json:"lines"`
+	Name     string    `json:"name"`
+	Format   string    `json:"format"`
+	Timezone string    `json:"timezone"`
+	Lines    []LogLine `json:"lines"`
+	Offset   int64     `json:"offset"`
+	Warnings []string  `json:"warnings"`
+}
+
+// ParseState is what the parser needs to pick up a log where it left off:
+// the format detected, the byte offset reached, the line counter and the
+// last entries, which are still open to overflow and stack lines.
+type ParseState struct {
+	// Options is the hash of the options the state was parsed with.
+	Options string       `json:"options"`
+	Format  string       `json:"format"`
+	Offset  int64        `json:"offset"`
+	Line    int          `json:"line"`
+	Num     int          `json:"num"`
+	Pending []LogLine    `json:"pending"`
+	Body    *payloadBody `json:"body"`
+	// Counted is the highest entry number added to the site's templates
+	// and error groups.
+	Counted int `json:"counted"`
 }
 
 type LogLine struct {
-	Num   int             `json:"num"`
-	Level *string         `json:"level"`
-	On    *time.Time      `json:"on_str"` // time gets converted to ISO string
-	Src   *string         `json:"src"`
-	Msg   string          `json:"msg"`
-	JSON  json.RawMessage `json:"json"`
-	Stack []string        `json:"stack"`
-	Raw   string          `json:"raw"`
+	Num         int               `json:"num"`
+	Level       *string           `json:"level"`
+	On          *time.Time        `json:"on_str"` // time gets converted to ISO string
+	Uptime      bool              `json:"uptime"` // On is an offset since boot (dmesg)
+	Severity    Severity          `json:"severity"`
+	LevelRaw    *string           `json:"level_raw"` // level as written, before cleaning
+	Src         *string           `json:"src"`
+	Msg         string            `json:"msg"`
+	JSON        json.RawMessage   `json:"json"`
+	XML         string            `json:"xml"`
+	Payloads    []Payload         `json:"payloads"` // JSON left inside Msg
+	Stack       []string          `json:"stack"`
+	Frames      []StackFrame      `json:"frames"`
+	Goroutines  []GoroutineGroup  `json:"goroutines"`
+	Fields      map[string]string `json:"fields"`
+	Tags        []string          `json:"tags"`
+	TemplateID  int               `json:"template"`
+	StartLine   int               `json:"start_line"` // lines and bytes of the entry in the file
+	EndLine     int               `json:"end_line"`
+	ByteOffset  int64             `json:"byte_offset"`
+	ByteLength  int64             `json:"byte_length"`
+	Fingerprint string            `json:"fingerprint"`
+	Raw         string            `json:"raw"`
+}
+
+// ParseOptions carries the per-site settings that change how a log is
+// parsed.
+type ParseOptions struct {
+	Transformers []LogTransform
+	JSONKeys     JSONKeys
+	// Location is used for timestamps that carry no offset of their own.
+	Location     *time.Location
+	TimeLayouts  []TimeLayout
+	LevelAliases map[string]string
+	// AppPackages are the prefixes of the site's own code in stack frames.
+	AppPackages []string
+	// EntryStart and Continuation are regexes that override how lines are
+	// grouped into entries, and MaxEntryLines caps an entry's size.
+	EntryStart    string
+	Continuation  string
+	MaxEntryLines int
+	Redaction     Redaction
+	// Format picks the site's log format instead of detecting it, Grok is
+	// the expression of the grok format with the site's own patterns.
+	Format       string
+	Grok         string
+	GrokPatterns map[string]string
+}
+
+func (opts ParseOptions) location() *time.Location {
+	if opts.Location == nil {
+		return time.UTC
+	}
+	return opts.Location
+}
+
+// hash identifies the options, so a log parsed with other options is not
+// resumed.
+func (opts ParseOptions) hash() string {
+	data, _ := json.Marshal(struct {
+		ParseOptions
+		Location string
+	}{opts, opts.location().String()})
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (opts ParseOptions) timeParser() timeParser {
+	tp := timeParser{loc: opts.location()}
+	for _, layout := range opts.TimeLayouts {
+		tp.formats = append(tp.formats, layout.timeFormat())
+	}
+	return tp
 }
 
+// LogTransform is a rule applied to the lines of the logs whose name
+// matches FileNames. Without an Action it replaces Find with Replace, or
+// the whole line with Replace, and drops the line if Replace is empty.
+// With a Field it runs after parsing on that field of the entries, as do
+// drop and keep-only on the whole entry.
 type LogTransform struct {
 	FileNames string `json:"filenames"`
 	Match     string `json:"match"`
 	Find      string `json:"find"`
 	Replace   string `json:"replace"`
+	Action    string `json:"action"`
+	Field     string `json:"field"`
+	Value     string `json:"value"`
 }
 
 type CompiledTransformer struct {
@@ -39,6 +141,9 @@ type CompiledTransformer struct {
 	Match     *regexp.Regexp
 	Find      *regexp.Regexp
 	Replace   string
+	Action    string
+	Field     string
+	Value     string
 }
 
 type jsonX struct {
@@ -46,55 +151,388 @@ type jsonX struct {
 	JSON json.RawMessage `json:"json"`
 }
 
-func ParseLog(logfile string, transformers []LogTransform) (*Log, error) {
-	data, err := os.ReadFile(logfile)
+func ParseLog(logfile string, opts ParseOptions) (*Log, error) {
+	log, _, err := parseLogFile(logfile, opts, nil)
+	return log, err
+}
+
+// parseLogFile parses logfile from the given state, or from the start if
+// state is nil, and returns the entries found along with the state to
+// resume from next time. When resuming, the entry that was open is
+// returned again as it may have picked up more lines.
+func parseLogFile(logfile string, opts ParseOptions, state *ParseState) (*Log, *ParseState, error) {
+	file, err := os.Open(logfile)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	if state == nil {
+		state = &ParseState{}
+	} else {
+		s := *state
+		state = &s
+		if _, err := file.Seek(state.Offset, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
 	}
-	name := filepath.Base(logfile)
-	lines := strings.FieldsFunc(string(data), func(c rune) bool { return c == '\n' || c == '\r' })
-	lines, transformerError := applyTransformers(transformers, name, lines)
 
 	log := Log{
-		Name:  name,
+		Name:  filepath.Base(logfile),
 		Lines: []LogLine{},
 	}
-	for _, line := range lines {
-		ll := parseLogLine(line)
+	err = ParseLogFrom(file, log.Name, opts, state, func(ll LogLine) {
+		log.Lines = append(log.Lines, ll)
+	})
+	log.Format = state.Format
+	log.Timezone = opts.location().String()
+	log.Offset = state.Offset
+	log.Warnings = transformerWarnings(opts.Transformers)
+	return &log, state, err
+}
 
-		if ll.Msg == "" && ll.Src == nil && ll.On == nil && ll.Raw != "" {
+// ParseLogReader scans the log line by line, grouping overflow and stack
+// lines with the entry they belong to, and hands each completed LogLine to
+// emit in order.
+func ParseLogReader(r io.Reader, name string, opts ParseOptions, emit func(LogLine)) error {
+	return ParseLogFrom(r, name, opts, &ParseState{}, emit)
+}
+
+// ParseLogFrom continues parsing from state, with r positioned at
+// state.Offset, and leaves state ready for the next call. The last entry
+// is emitted but also kept open in state.
+func ParseLogFrom(r io.Reader, name string, opts ParseOptions, state *ParseState, emit func(LogLine)) error {
+	// rules that do not compile are skipped, see transformerWarnings
+	trs, _ := compileTransformers(opts.Transformers)
+	bounds, err := compileEntryBounds(opts)
+	if err != nil {
+		return err
+	}
 
-			if len(log.Lines) > 0 {
-				last := &log.Lines[len(log.Lines)-1]
-				addOverflowLine(&ll, last)
+	p := logParser{emit: emit, opts: opts, levels: newLevelMatcher(opts.LevelAliases), bounds: bounds, trs: trs, redact: newRedactor(opts.Redaction), name: name, num: state.Num}
+	p.at.Line = state.Line
+	for _, ll := range state.Pending {
+		ll := ll
+		p.pending = append(p.pending, &ll)
+		p.open = &ll
+	}
+	if state.Body != nil && p.open != nil {
+		body := *state.Body
+		p.body = &body
+	}
+	if state.Format != "" {
+		p.format = findLogFormat(state.Format, opts)
+	} else if opts.Format != "" {
+		p.format = findLogFormat(opts.Format, opts)
+	}
+	offset := state.Offset
+	start := offset
+	terminated := true
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineSize)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := scanLogLines(data, atEOF)
+		start = offset
+		offset += int64(advance)
+		terminated = advance > len(token)
+		return advance, token, err
+	})
+	saved := false
+	save := func(offset int64, line int) {
+		p.ready()
+		state.Options = opts.hash()
+		state.Format = p.format.Name()
+		state.Offset = offset
+		state.Line = line
+		state.Num = p.num
+		state.Pending = nil
+		state.Body = nil
+		for _, ll := range p.pending {
+			pending := *ll
+			pending.Stack = append([]string(nil), ll.Stack...)
+			// the state is cached on disk so it is redacted as well
+			if ll == p.open && p.body != nil {
+				body := *p.body
+				p.redact.openEntry(&pending, &body)
+				state.Body = &body
 			} else {
-				addOverflowLine(&ll, &ll)
-				log.Lines = append(log.Lines, ll)
+				p.redact.entry(&pending)
 			}
+			state.Pending = append(state.Pending, pending)
+		}
+		saved = true
+	}
+	line := state.Line
+	// joined is a line held back to be joined with the next one
+	var joined *sampledLine
+	resumeAt := func(offset int64, line int) {
+		if joined != nil {
+			save(joined.at.Offset, joined.at.First-1)
+		} else {
+			save(offset, line)
+		}
+	}
+	for scanner.Scan() {
+		text := scanner.Text()
+		if !terminated {
+			// the last line is still being written so resume from its start
+			resumeAt(start, line)
+		}
+		line++
+		at := lineSpan{First: line, Line: line, Offset: start, Length: int64(len(text))}
+		if len(text) == 0 {
+			// blank lines are not transformed but kept within entries
+			p.push(text, at)
+			continue
+		}
+		text, keep, join := transformLine(trs, name, text)
+		if !keep {
+			continue
+		}
+		if joined != nil {
+			at.Length += at.Offset - joined.at.Offset
+			at.Offset, at.First = joined.at.Offset, joined.at.First
+			text = joined.text + text
+			joined = nil
+		}
+		if join {
+			joined = &sampledLine{text + joinSeparator(trs, name, text), at}
+			continue
+		}
+		p.push(text, at)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
 
-		} else if len(log.Lines) > 0 {
+	if !saved {
+		resumeAt(offset, line)
+	}
+	if joined != nil {
+		p.push(strings.TrimRight(joined.text, " "), joined.at)
+	}
+	p.flush()
 
-			last := &log.Lines[len(log.Lines)-1]
-			if (ll.On == nil && ll.Level == nil) && (last.On != nil || last.Level != nil) {
-				addOverflowLine(&ll, last)
-			} else {
-				log.Lines = append(log.Lines, ll)
+	return nil
+}
+
+const maxLogLineSize = 64 * 1024 * 1024
+
+// scanLogLines is a bufio.SplitFunc that treats '\n', '\r' and "\r\n" as
+// line terminators.
+func scanLogLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\r' {
+			if i+1 == len(data) && !atEOF {
+				return 0, nil, nil
+			}
+			if i+1 < len(data) && data[i+1] == '\n' {
+				return i + 2, data[:i], nil
 			}
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
 
-		} else {
-			log.Lines = append(log.Lines, ll)
+type logParser struct {
+	emit   func(LogLine)
+	opts   ParseOptions
+	levels levelMatcher
+	bounds entryBounds
+	format LogFormat
+	trs    []*CompiledTransformer
+	redact *redactor
+	name   string
+	sample []sampledLine
+	// at is where the line being fed is in the file and blanks the
+	// blank lines just before it.
+	at     lineSpan
+	blanks int
+	// pending are the entries not yet emitted, oldest first, as lines
+	// from interleaved threads may still belong to them. open is the
+	// latest.
+	pending []*LogLine
+	open    *LogLine
+	body    *payloadBody
+	num     int
+}
+
+// lineSpan is where a line is in the file. Lines joined by a transformer
+// span from line First.
+type lineSpan struct {
+	First  int
+	Line   int
+	Offset int64
+	Length int64
+}
+
+type sampledLine struct {
+	text string
+	at   lineSpan
+}
+
+// push holds back the first lines of a log until there are enough to
+// detect its format.
+func (p *logParser) push(line string, at lineSpan) {
+	if p.format != nil {
+		p.at = at
+		p.feed(line)
+		return
+	}
+	p.sample = append(p.sample, sampledLine{line, at})
+	if len(p.sample) >= formatSampleSize {
+		p.ready()
+	}
+}
+
+// ready picks the format from whatever sample has been collected and
+// parses the lines held back for it.
+func (p *logParser) ready() {
+	if p.format != nil {
+		return
+	}
+	sample := []string{}
+	for _, line := range p.sample {
+		if line.text != "" {
+			sample = append(sample, line.text)
+		}
+	}
+	p.format = detectLogFormat(sample, p.opts)
+	for _, line := range p.sample {
+		p.at = line.at
+		p.feed(line.text)
+	}
+	p.sample = nil
+}
+
+func (p *logParser) feed(line string) {
+	if p.body != nil {
+		if !p.startsEntry(line) {
+			p.feedBody(line)
+			return
+		}
+		p.body = nil
+	}
+	if line == "" {
+		p.blanks++
+		return
+	}
+	p.feedLine(line)
+	p.blanks = 0
+	p.openBody(line)
+}
+
+func (p *logParser) feedLine(line string) {
+	if continues, ok := p.bounds.continues(line); ok {
+		ll := p.format.ParseLine(line)
+		if continues && p.open != nil && !p.bounds.full(p.open) {
+			p.overflow(&ll, p.open)
+			return
+		}
+		if ll.Msg == "" {
+			ll.Msg = ll.Raw
+		}
+		p.start(ll)
+		return
+	}
+
+	if p.feedGoDump(line) {
+		return
+	}
+
+	ll := p.format.ParseLine(line)
+
+	if p.format.IsContinuation(ll, p.open) {
+		if p.open != nil {
+			target := p.continuationTarget(line)
+			if !p.bounds.full(target) {
+				p.overflow(&ll, target)
+				return
+			}
 		}
+		addOverflowLine(&ll, &ll)
+	}
+	p.start(ll)
+}
+
+// start opens a new entry. Entries without a thread close everything
+// before them, otherwise older entries are emitted once their thread has
+// moved on or they fall out of the window.
+func (p *logParser) start(ll LogLine) {
+	if threadOf(&ll) == "" {
+		p.flush()
+	}
+	p.num++
+	ll.Num = p.num
+	ll.StartLine = p.at.First
+	ll.ByteOffset = p.at.Offset
+	p.extend(&ll)
+	p.pending = append(p.pending, &ll)
+	p.open = &ll
+	for len(p.pending) > 1 && (len(p.pending) > threadWindow || p.closed(0)) {
+		p.emitFirst()
 	}
+}
 
-	for i := 0; i < len(log.Lines); i++ {
-		ll := &log.Lines[i]
-		ll.Num = i + 1
+func (p *logParser) flush() {
+	for len(p.pending) > 0 {
+		p.emitFirst()
+	}
+	p.body = nil
+}
+
+func (p *logParser) emitFirst() {
+	ll := *p.pending[0]
+	p.pending = p.pending[1:]
+	if len(p.pending) == 0 {
+		p.open = nil
+	}
+	if ll.LevelRaw == nil {
+		ll.LevelRaw = ll.Level
+	}
+	if !transformEntry(p.trs, p.name, &ll) {
+		return
+	}
+	ll.Severity = p.levels.severity(ll.Level)
+	if ll.JSON == nil {
 		xtract := xtractJSON(ll.Msg)
 		ll.Msg = xtract.Line
 		ll.JSON = xtract.JSON
 	}
+	p.redact.entry(&ll)
+	if len(ll.Stack) > 0 {
+		ll.Frames = parseStackFrames(ll.Stack, p.opts.AppPackages)
+		if isGoDump(&ll) {
+			ll.Goroutines = groupGoroutines(ll.Stack, p.opts.AppPackages)
+		}
+	}
+	ll.Payloads = findPayloads(ll.Msg)
+	ll.Fingerprint = fingerprint(&ll)
+	p.emit(ll)
+}
 
-	return &log, transformerError
+// overflow adds the line to an earlier entry, keeping the blank lines
+// before it in Raw.
+func (p *logParser) overflow(fromLL *LogLine, toLL *LogLine) {
+	raw := len(toLL.Raw)
+	addOverflowLine(fromLL, toLL)
+	if p.blanks > 0 && len(toLL.Raw) > raw {
+		toLL.Raw = toLL.Raw[:raw] + strings.Repeat("\n", p.blanks) + toLL.Raw[raw:]
+	}
+	p.extend(toLL)
+}
+
+// extend stretches the entry over the line being fed.
+func (p *logParser) extend(ll *LogLine) {
+	ll.EndLine = p.at.Line
+	ll.ByteLength = p.at.Offset + p.at.Length - ll.ByteOffset
 }
 
 func addOverflowLine(fromLL *LogLine, toLL *LogLine) {
@@ -118,6 +556,8 @@ func addOverflowLine(fromLL *LogLine, toLL *LogLine) {
 var stackRxx []*regexp.Regexp = []*regexp.Regexp{
 	regexp.MustCompile(`[.][A-Za-z0-9]*Exception:`),
 	regexp.MustCompile(`^\t+at\s`),
+	regexp.MustCompile(`^\s+at\s`),
+	regexp.MustCompile(`^\s*File ".*", line \d+`),
 	regexp.MustCompile(`^\s*Exception in`),
 	regexp.MustCompile(`^\s*Exception:`),
 	regexp.MustCompile(`^\s*Traceback\s`),
@@ -136,10 +576,10 @@ func isStackLine(msg string) bool {
 
 var splitRx *regexp.Regexp = regexp.MustCompile(`\s`)
 
-func parseLogLine(line string) LogLine {
+func parseLogLine(line string, tp timeParser, lm levelMatcher) LogLine {
 	ll := LogLine{Raw: line}
 
-	if line[0] == ' ' || line[0] == '\t' || line[0] == '}' {
+	if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '}' {
 		return ll
 	}
 
@@ -166,21 +606,26 @@ func parseLogLine(line string) LogLine {
 		if ll.On == nil {
 			var v *time.Time
 			var eaten_ int
-			v, tokens, eaten_ = popDatetime(tokens)
+			v, tokens, eaten_ = tp.popDatetimeAt(tokens, ll.Level == nil && ll.Src == nil)
 			eaten += eaten_
 			ll.On = v
 			if ll.On != nil {
+				ll.Uptime = isUptime(ll.On)
 				continue
 			}
 		}
 
 		if ll.Level == nil {
 			var v *string
+			var raw string
 			var eaten_ int
 			var pos int
-			v, tokens, eaten_, pos = popLevel(tokens, ll.On == nil)
+			v, raw, tokens, eaten_, pos = lm.popLevel(tokens, ll.On == nil)
 			eaten += eaten_
 			ll.Level = v
+			if v != nil {
+				ll.LevelRaw = &raw
+			}
 			if ll.Level != nil {
 				if pos > 0 {
 					tkns := []string{}
@@ -290,66 +735,104 @@ func isSource(token string) bool {
 	return false
 }
 
-func popLevel(tokens []string, onlyfirst bool) (*string, []string, int, int) {
-	levels := []string{"ERROR", "WARN", "DEBUG", "INFO", "TRACE", "INF", "ERR"}
+func (lm levelMatcher) popLevel(tokens []string, onlyfirst bool) (*string, string, []string, int, int) {
 	if len(tokens) == 0 {
-		return nil, tokens, 0, 0
+		return nil, "", tokens, 0, 0
 	}
 	lookahead := 3
 	if onlyfirst {
 		lookahead = 1
 	}
-	for _, v := range levels {
-		for i := 0; i < lookahead; i++ {
-
-			if v == tokens[i] {
-				return &v, append(tokens[:i], tokens[i+1:]...), len(v) + 1, i
-			}
+	if lookahead > len(tokens) {
+		lookahead = len(tokens)
+	}
+	for i := 0; i < lookahead; i++ {
+		v, rest, ok := lm.matchLevel(tokens[i], i == 0, !onlyfirst)
+		if !ok {
+			continue
+		}
+		if rest != "" {
+			tokens[i] = rest
+			return &v, v, tokens, len(v) + 1, i
 		}
+		raw := tokens[i]
+		eaten := len(tokens[i]) + 1
+		return &v, raw, append(tokens[:i], tokens[i+1:]...), eaten, i
 	}
-	return nil, tokens, 0, 0
+	return nil, "", tokens, 0, 0
 }
 
 var cleanRx *regexp.Regexp = regexp.MustCompile("[^0-9:]")
 
+// timeParser reads timestamps, trying the site's own formats before the
+// built-in ones and placing those without an offset in loc.
+type timeParser struct {
+	loc     *time.Location
+	formats []timeFormat
+}
+
 func popDatetime(tokens []string) (*time.Time, []string, int) {
+	return timeParser{loc: time.UTC}.popDatetime(tokens)
+}
+
+func (tp timeParser) popDatetime(tokens []string) (*time.Time, []string, int) {
+	return tp.popDatetimeAt(tokens, true)
+}
+
+// popDatetimeAt only reads epoch and uptime numbers when leading, at the
+// start of the line, as further along a number is more likely a count.
+func (tp timeParser) popDatetimeAt(tokens []string, leading bool) (*time.Time, []string, int) {
+
+	if t, rest, eaten := tp.popFormats(tp.formats, tokens); t != nil {
+		return t, rest, eaten
+	}
 
-	formats := []struct {
-		format     string
-		tokenCount int
-	}{
-		// W3C format: %Y-%m-%d %H:%M:%S
-		{"2006-01-02 15:04:05", 2},
-		// Standard golang formats
-		{"2006-01-02T15:04:05Z0700", 1},
-		{time.RFC3339, 1},
-		{time.RFC1123Z, 6},
-		{time.RFC1123, 6},
-		{time.RFC850, 4},
-		{time.RFC822Z, 5},
-		{time.RFC822, 5},
-		{time.RubyDate, 6},
-		{time.UnixDate, 6},
-		{time.ANSIC, 5},
-		// Common format 1: %d/%b/%Y %H:%M:%S
-		{"02/Jan/2006 15:04:05", 2},
-		// Less common format: %d/%b/%Y:%H:%M:%S
-		{"02/Jan/2006:15:04:05", 1},
-		// IIS format: %m/%d/%Y, %H:%M:%S
-		{"01/02/2006, 15:04:05", 2},
-		// Common format 2: %d %b %Y %H:%M:%S
-		{"02 Jan 2006 15:04:05", 4},
-		// Common format 3: %Y %b %d %H:%M:%S
-		{"2006 Jan 02 15:04:05", 4},
-		// ISO 8601 format: %Y-%m-%d %H:%M:%S
-		{"2006-01-02 15:04:05", 2},
-		// RFC 3164 format: %b %d %H:%M:%S (default current year)
-		{"Jan 02 15:04:05", 3},
-		// Db2 format: %Y-%m-%d-%H.%M.%S
-		{"2006-01-02-15.04.05Z07:00", 1},
-		{"2006-01-02-15.04.05Z0700", 1},
+	if t, ok := popNumericTime(tokens); ok && leading {
+		return t, tokens[1:], len(tokens[0]) + 1
 	}
 
+	return tp.popFormats(builtinTimeFormats, tokens)
+}
+
+type timeFormat struct {
+	format     string
+	tokenCount int
+}
+
+var builtinTimeFormats = []timeFormat{
+	// W3C format: %Y-%m-%d %H:%M:%S
+	{"2006-01-02 15:04:05", 2},
+	// Standard golang formats
+	{"2006-01-02T15:04:05Z0700", 1},
+	{time.RFC3339, 1},
+	{time.RFC1123Z, 6},
+	{time.RFC1123, 6},
+	{time.RFC850, 4},
+	{time.RFC822Z, 5},
+	{time.RFC822, 5},
+	{time.RubyDate, 6},
+	{time.UnixDate, 6},
+	{time.ANSIC, 5},
+	// Common format 1: %d/%b/%Y %H:%M:%S
+	{"02/Jan/2006 15:04:05", 2},
+	// Less common format: %d/%b/%Y:%H:%M:%S
+	{"02/Jan/2006:15:04:05", 1},
+	// IIS format: %m/%d/%Y, %H:%M:%S
+	{"01/02/2006, 15:04:05", 2},
+	// Common format 2: %d %b %Y %H:%M:%S
+	{"02 Jan 2006 15:04:05", 4},
+	// Common format 3: %Y %b %d %H:%M:%S
+	{"2006 Jan 02 15:04:05", 4},
+	// ISO 8601 format: %Y-%m-%d %H:%M:%S
+	{"2006-01-02 15:04:05", 2},
+	// RFC 3164 format: %b %d %H:%M:%S (default current year)
+	{"Jan 02 15:04:05", 3},
+	// Db2 format: %Y-%m-%d-%H.%M.%S
+	{"2006-01-02-15.04.05Z07:00", 1},
+	{"2006-01-02-15.04.05Z0700", 1},
+}
+
+func (tp timeParser) popFormats(formats []timeFormat, tokens []string) (*time.Time, []string, int) {
 	for _, f := range formats {
 
 		if len(tokens) > f.tokenCount {
@@ -358,7 +841,7 @@ func popDatetime(tokens []string) (*time.Time, []string, int) {
 			if strings.HasPrefix(p, "[") && strings.HasSuffix(p, "]") {
 				p = p[1 : len(p)-1]
 			}
-			t, err := time.Parse(f.format, p)
+			t, err := time.ParseInLocation(f.format, p, tp.loc)
 			if err == nil {
 				if t.Year() < 1900 {
 					t = time.Date(time.Now().Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
@@ -371,30 +854,149 @@ func popDatetime(tokens []string) (*time.Time, []string, int) {
 	return nil, tokens, 0
 }
 
-func applyTransformers(transformers []LogTransform, name string, lines []string) ([]string, error) {
-	if transformers == nil {
-		return lines, nil
+// parseValue parses a value that should be nothing but a timestamp.
+func (tp timeParser) parseValue(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if t, err := time.ParseInLocation(time.RFC3339Nano, value, tp.loc); err == nil {
+		return &t
 	}
+	tokens := append(strings.Fields(value), "")
+	t, rest, _ := tp.popDatetime(tokens)
+	if t == nil || len(rest) != 1 {
+		return nil
+	}
+	return t
+}
+
+var uptimeRx *regexp.Regexp = regexp.MustCompile(`^\[?(\d+)\.(\d{6})\]$`)
 
+// uptimeBase is where dmesg style uptime stamps are counted from, so they
+// read as an offset since boot rather than a date.
+var uptimeBase time.Time = time.Unix(0, 0).UTC()
+
+func isUptime(t *time.Time) bool {
+	return t != nil && t.Before(uptimeBase.AddDate(1, 0, 0))
+}
+
+// popNumericTime reads a leading Unix epoch timestamp or a dmesg uptime
+// like [12345.678901].
+func popNumericTime(tokens []string) (*time.Time, bool) {
+	if len(tokens) < 2 {
+		return nil, false
+	}
+	token := tokens[0]
+	if m := uptimeRx.FindStringSubmatch(token); m != nil {
+		secs, _ := strconv.ParseInt(m[1], 10, 64)
+		micros, _ := strconv.ParseInt(m[2], 10, 64)
+		t := uptimeBase.Add(time.Duration(secs)*time.Second + time.Duration(micros)*time.Microsecond)
+		return &t, true
+	}
+	if strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]") {
+		token = token[1 : len(token)-1]
+	}
+	if t, ok := epochTime(token); ok {
+		return &t, true
+	}
+	return nil, false
+}
+
+var epochRx *regexp.Regexp = regexp.MustCompile(`^(\d{9,19})(?:\.(\d{1,9}))?$`)
+
+// epochTime reads a Unix timestamp, deciding from the number of digits
+// whether it is in seconds, milliseconds, microseconds or nanoseconds.
+// Anything that lands outside 1990-2100 is taken to be some other number.
+func epochTime(value string) (time.Time, bool) {
+	m := epochRx.FindStringSubmatch(value)
+	if m == nil {
+		return time.Time{}, false
+	}
+	var unit int64
+	switch {
+	case len(m[1]) <= 10:
+		unit = int64(time.Second)
+	case len(m[1]) <= 13:
+		unit = int64(time.Millisecond)
+	case len(m[1]) <= 16:
+		unit = int64(time.Microsecond)
+	default:
+		unit = int64(time.Nanosecond)
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil || n > math.MaxInt64/unit {
+		return time.Time{}, false
+	}
+	frac, _ := strconv.ParseInt((m[2] + "000000000")[:9], 10, 64)
+	t := time.Unix(0, n*unit+frac*unit/int64(time.Second)).UTC()
+	if t.Year() < 1990 || t.Year() > 2100 {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// TransformerError is a transformer rule that does not compile, by its
+// index and the field at fault.
+type TransformerError struct {
+	Rule  int    `json:"rule"`
+	Field string `json:"field"`
+	Err   string `json:"error"`
+}
+
+func (e *TransformerError) Error() string {
+	return fmt.Sprintf("transformer %d: invalid %s: %s", e.Rule, e.Field, e.Err)
+}
+
+// compileTransformers returns the rules that compile, along with the
+// errors of those that do not joined into one.
+func compileTransformers(transformers []LogTransform) ([]*CompiledTransformer, error) {
 	trs := []*CompiledTransformer{}
 	errs := []error{}
-	for _, transformer := range transformers {
+	for i, transformer := range transformers {
 		ct, err := compile(transformer)
 		if err != nil {
+			var te *TransformerError
+			if errors.As(err, &te) {
+				te.Rule = i
+			}
 			errs = append(errs, err)
 		} else {
 			trs = append(trs, ct)
 		}
 	}
+	return trs, errors.Join(errs...)
+}
 
-	for _, tr := range trs {
-		lines = applyTransformer(tr, name, lines)
+// transformerWarnings lists the rules that are skipped as they do not
+// compile.
+func transformerWarnings(transformers []LogTransform) []string {
+	warnings := []string{}
+	_, err := compileTransformers(transformers)
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			warnings = append(warnings, err.Error())
+		}
 	}
+	return warnings
+}
 
-	if len(errs) > 0 {
-		return nil, errs[0]
+// transformLine applies the raw line rules in order. It returns the new
+// line, whether to keep it and whether to join it with the next line.
+func transformLine(trs []*CompiledTransformer, name string, line string) (string, bool, bool) {
+	join := false
+	for _, tr := range trs {
+		if tr.onEntry() {
+			continue
+		}
+		if tr.Action == "join-with-next" {
+			join = join || tr.matches(name, line)
+			continue
+		}
+		var keep bool
+		line, keep = applyTransformer(tr, name, line)
+		if !keep {
+			return "", false, false
+		}
 	}
-	return lines, nil
+	return line, true, join
 }
 
 func compile(t LogTransform) (*CompiledTransformer, error) {
@@ -402,54 +1004,81 @@ func compile(t LogTransform) (*CompiledTransformer, error) {
 	if t.FileNames != "" {
 		r, err := regexp.Compile(t.FileNames)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to compile transformer: %s", t.FileNames)
+			return nil, &TransformerError{Field: "FileNames", Err: err.Error()}
 		}
 		ret.FileNames = r
 	}
 	if t.Match != "" {
 		r, err := regexp.Compile(t.Match)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to compile transformer: %s", t.Match)
+			return nil, &TransformerError{Field: "Match", Err: err.Error()}
 		}
 		ret.Match = r
 	}
 	if t.Find != "" {
 		r, err := regexp.Compile(t.Find)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to compile transformer: %s", t.Find)
+			return nil, &TransformerError{Field: "Find", Err: err.Error()}
 		}
 		ret.Find = r
 	}
 
 	ret.Replace = t.Replace
+	ret.Action = t.Action
+	ret.Field = t.Field
+	ret.Value = t.Value
+
+	switch t.Action {
+	case "", "replace", "drop", "keep-only", "mask":
+	case "join-with-next":
+		if t.Field != "" {
+			return nil, &TransformerError{Field: "Field", Err: fmt.Sprintf("join-with-next works on lines, not field %s", t.Field)}
+		}
+	case "set-level", "add-tag":
+		if t.Value == "" {
+			return nil, &TransformerError{Field: "Value", Err: fmt.Sprintf("%s needs a value", t.Action)}
+		}
+	case "extract":
+		if ret.extractRx() == nil || len(ret.extractRx().SubexpNames()) < 2 {
+			return nil, &TransformerError{Field: "Find", Err: "extract needs a pattern with named groups"}
+		}
+	default:
+		return nil, &TransformerError{Field: "Action", Err: fmt.Sprintf("unknown action %s", t.Action)}
+	}
 
 	return &ret, nil
 }
 
-func applyTransformer(transformer *CompiledTransformer, name string, lines []string) []string {
+func applyTransformer(transformer *CompiledTransformer, name string, line string) (string, bool) {
 
 	if transformer.FileNames != nil && transformer.FileNames.FindStringIndex(name) == nil {
-		return lines
+		return line, true
 	}
 
-	ret := []string{}
-	for _, line := range lines {
-
-		if transformer.Match != nil && transformer.Match.FindStringIndex(line) == nil {
-			ret = append(ret, line)
-			continue
+	switch transformer.Action {
+	case "replace":
+		if !transformer.matches(name, line) {
+			return line, true
 		}
-
 		if transformer.Find != nil {
-			line = transformer.Find.ReplaceAllString(line, transformer.Replace)
-			ret = append(ret, line)
-		} else {
-			if transformer.Replace != "" {
-				ret = append(ret, transformer.Replace)
-			}
+			return transformer.Find.ReplaceAllString(line, transformer.Replace), true
 		}
+		return transformer.Replace, true
+	case "mask":
+		return transformer.mask(line), true
+	}
+
+	if transformer.Match != nil && transformer.Match.FindStringIndex(line) == nil {
+		return line, true
+	}
+
+	if transformer.Find != nil {
+		return transformer.Find.ReplaceAllString(line, transformer.Replace), true
+	}
+	if transformer.Replace != "" {
+		return transformer.Replace, true
 	}
-	return ret
+	return "", false
 }
 
 func xtractJSON(line string) jsonX {
@@ -2,7 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -30,8 +33,8 @@ func TestLogParser(t *testing.T) {
 			Match: "78",
 		},
 	}
-	log, err := ParseLog(logfile, transformers)
-	log, err = ParseLog(logfile, nil)
+	log, err := ParseLog(logfile, ParseOptions{Transformers: transformers})
+	log, err = ParseLog(logfile, ParseOptions{})
 	if err != nil {
 		fmt.Println(err)
 	} else {
@@ -118,3 +121,259 @@ func TestJsonExtractor(testing *testing.T) {
 		fmt.Println(string(json))
 	}
 }
+
+func TestParseLogReader(testing *testing.T) {
+	data := "2024-12-30 04:26:55,377 11105309 [qtp-105] WARN  com.r2.ui.Login - Login Failed\r\n" +
+		"com.r2.util.LoginException: Invalid Login Credentials\n" +
+		"\tat com.r2.ui.UserSession.authenticate(UserSession.java:46)\n" +
+		"\n" +
+		"2024-12-30 04:27:26,415 11136347 [qtp-80] DEBUG com.r2.ui.dashboard.RedashApiUtil - Posting {\"a\":1}\n"
+
+	lines := []LogLine{}
+	err := ParseLogReader(strings.NewReader(data), "test.log", ParseOptions{}, func(ll LogLine) {
+		lines = append(lines, ll)
+	})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(lines) != 2 {
+		testing.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	if lines[0].Num != 1 || lines[1].Num != 2 {
+		testing.Errorf("Line numbers incorrect: %d, %d", lines[0].Num, lines[1].Num)
+	}
+	if len(lines[0].Stack) != 2 {
+		testing.Errorf("Expected 2 stack lines, got %d", len(lines[0].Stack))
+	}
+	if string(lines[1].JSON) != `{"a":1}` {
+		testing.Errorf("JSON not extracted: %s", lines[1].JSON)
+	}
+}
+
+func TestParseLogResume(testing *testing.T) {
+	data, err := os.ReadFile("sample_test.log")
+	if err != nil {
+		testing.Fatal(err)
+	}
+	full, err := ParseLog("sample_test.log", ParseOptions{})
+	if err != nil {
+		testing.Fatal(err)
+	}
+
+	logfile := filepath.Join(testing.TempDir(), "resume.log")
+	half := len(data) / 2
+	if err := os.WriteFile(logfile, data[:half], 0644); err != nil {
+		testing.Fatal(err)
+	}
+	first, state, err := parseLogFile(logfile, ParseOptions{}, nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if state.Offset > int64(half) || data[state.Offset-1] != '\n' {
+		testing.Errorf("Offset should be at the end of the last complete line: %d", state.Offset)
+	}
+
+	if err := os.WriteFile(logfile, data, 0644); err != nil {
+		testing.Fatal(err)
+	}
+	update, _, err := parseLogFile(logfile, ParseOptions{}, state)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(state.Pending) == 0 || update.Lines[0].Num != state.Pending[0].Num {
+		testing.Errorf("Update should start with the pending lines")
+	}
+
+	lines := append(first.Lines[:update.Lines[0].Num-1], update.Lines...)
+	got, _ := json.Marshal(lines)
+	expected, _ := json.Marshal(full.Lines)
+	if string(got) != string(expected) {
+		testing.Errorf("Resumed parse differs from full parse")
+	}
+}
+
+func TestParseOptionsHash(testing *testing.T) {
+	opts := ParseOptions{Transformers: []LogTransform{{Find: "a", Replace: "b"}}, LevelAliases: map[string]string{"x": "error", "y": "warn"}}
+	same := ParseOptions{Transformers: []LogTransform{{Find: "a", Replace: "b"}}, LevelAliases: map[string]string{"y": "warn", "x": "error"}}
+	if opts.hash() != same.hash() {
+		testing.Error("Same options should hash the same")
+	}
+	changed := []ParseOptions{
+		{LevelAliases: opts.LevelAliases},
+		{Transformers: opts.Transformers, LevelAliases: opts.LevelAliases, Redaction: Redaction{Enabled: true}},
+		{Transformers: opts.Transformers, LevelAliases: opts.LevelAliases, Location: time.FixedZone("X", 3600)},
+	}
+	for _, other := range changed {
+		if other.hash() == opts.hash() {
+			testing.Errorf("Changed options should hash differently: %+v", other)
+		}
+	}
+
+	_, state, err := parseLogFile("sample_test.log", opts, nil)
+	if err != nil || state.Options != opts.hash() {
+		testing.Errorf("State should record the options hash: %v", err)
+	}
+}
+
+func TestLineSpans(testing *testing.T) {
+	data := "2022-04-17 11:25:12 INFO first\r\n\r\n2022-04-17 11:25:13 ERROR second\n  detail one\n\n  detail two\n\n2022-04-17 11:25:14 INFO third"
+	logfile := filepath.Join(testing.TempDir(), "spans.log")
+	if err := os.WriteFile(logfile, []byte(data), 0644); err != nil {
+		testing.Fatal(err)
+	}
+	log, state, err := parseLogFile(logfile, ParseOptions{}, nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(log.Lines) != 3 || state.Line != 7 {
+		testing.Fatalf("Expected 3 entries over 7 lines, got %d, %d: %+v", len(log.Lines), state.Line, log.Lines)
+	}
+
+	expected := []struct {
+		start, end int
+		raw        string
+	}{
+		{1, 1, "2022-04-17 11:25:12 INFO first"},
+		{3, 6, "2022-04-17 11:25:13 ERROR second\n  detail one\n\n  detail two"},
+		{8, 8, "2022-04-17 11:25:14 INFO third"},
+	}
+	for i, ll := range log.Lines {
+		if ll.StartLine != expected[i].start || ll.EndLine != expected[i].end {
+			testing.Errorf("Bad lines for entry %d: %d-%d", i, ll.StartLine, ll.EndLine)
+		}
+		original, err := readLogBytes(logfile, ll.ByteOffset, ll.ByteLength)
+		if err != nil {
+			testing.Fatal(err)
+		}
+		if string(original) != expected[i].raw || ll.Raw != expected[i].raw {
+			testing.Errorf("Bad bytes for entry %d: %q, raw %q", i, original, ll.Raw)
+		}
+	}
+}
+
+func TestTransformerErrors(testing *testing.T) {
+	transformers := []LogTransform{
+		{Match: "("},
+		{Find: "secret", Replace: "***"},
+		{FileNames: "[", Action: "drop"},
+		{Action: "explode"},
+	}
+	_, err := compileTransformers(transformers)
+	var te *TransformerError
+	if !errors.As(err, &te) || te.Rule != 0 || te.Field != "Match" {
+		testing.Errorf("First error should be on rule 0 Match: %v", err)
+	}
+
+	logfile := filepath.Join(testing.TempDir(), "rules.log")
+	if err := os.WriteFile(logfile, []byte("2022-04-17 11:25:12 INFO the secret\n"), 0644); err != nil {
+		testing.Fatal(err)
+	}
+	log, _, err := parseLogFile(logfile, ParseOptions{Transformers: transformers}, nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(log.Lines) != 1 || log.Lines[0].Msg != "the ***" {
+		testing.Errorf("Valid rules should still apply: %+v", log.Lines)
+	}
+	expected := []string{"transformer 0: invalid Match", "transformer 2: invalid FileNames", "transformer 3: invalid Action"}
+	if len(log.Warnings) != len(expected) {
+		testing.Fatalf("Expected a warning per bad rule: %v", log.Warnings)
+	}
+	for i, warning := range log.Warnings {
+		if !strings.HasPrefix(warning, expected[i]) {
+			testing.Errorf("Bad warning %d: %s", i, warning)
+		}
+	}
+}
+
+func TestReadLogBytes(testing *testing.T) {
+	logfile := filepath.Join(testing.TempDir(), "bytes.log")
+	if err := os.WriteFile(logfile, []byte("0123456789"), 0644); err != nil {
+		testing.Fatal(err)
+	}
+	if data, err := readLogBytes(logfile, 6, 4); err != nil || string(data) != "6789" {
+		testing.Errorf("Range up to the end should be read: %q %v", data, err)
+	}
+	if data, err := readLogBytes(logfile, 8, 100); err != nil || string(data) != "89" {
+		testing.Errorf("Range past the end should give what there is: %q %v", data, err)
+	}
+	for _, bad := range [][2]int64{{-1, 2}, {0, -2}, {11, 1}, {0, maxLogLineSize + 1}} {
+		if _, err := readLogBytes(logfile, bad[0], bad[1]); err == nil {
+			testing.Errorf("Bad range should be rejected: %v", bad)
+		}
+	}
+}
+
+func TestDateParserLocation(testing *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		testing.Skip("no tzdata: ", err)
+	}
+	tp := timeParser{loc: loc}
+
+	std := time.Date(2022, 04, 17, 11, 25, 12, 345*1e6, loc)
+	t, _, _ := tp.popDatetime(strings.Fields("2022-04-17 11:25:12.345 No offset"))
+	if t == nil || !t.Equal(std) {
+		testing.Errorf("Location not applied: %v", t)
+	}
+
+	std = time.Date(2022, 04, 17, 11, 25, 12, 345*1e6, time.FixedZone("Custom", -10*60*60))
+	t, _, _ = tp.popDatetime(strings.Fields("2022-04-17T11:25:12.345-10:00 With offset"))
+	if t == nil || !t.Equal(std) {
+		testing.Errorf("Offset not kept: %v", t)
+	}
+
+	lines, _ := parseLines(testing, "2022-04-17 11:25:12 INFO started\n", ParseOptions{Location: loc})
+	if lines[0].On == nil || lines[0].On.Location() != loc {
+		testing.Errorf("Location not used by parser: %v", lines[0].On)
+	}
+
+	log, err := ParseLog("sample_test.log", ParseOptions{Location: loc})
+	if err != nil || log.Timezone != "America/New_York" {
+		testing.Errorf("Timezone not reported: %v %v", log.Timezone, err)
+	}
+}
+
+func TestNumericDateParser(testing *testing.T) {
+	std := time.Date(2024, 04, 17, 11, 25, 12, 0, time.UTC)
+	tests := []struct {
+		line string
+		std  time.Time
+	}{
+		{"1713353112 epoch seconds", std},
+		{"1713353112.345678 epoch fraction", std.Add(345678 * time.Microsecond)},
+		{"1713353112345 epoch millis", std.Add(345 * time.Millisecond)},
+		{"1713353112345678 epoch micros", std.Add(345678 * time.Microsecond)},
+		{"1713353112345678901 epoch nanos", std.Add(345678901 * time.Nanosecond)},
+		{"[1713353112] bracketed", std},
+	}
+	for _, test := range tests {
+		t, _, _ := popDatetime(strings.Fields(test.line))
+		if t == nil || !t.Equal(test.std) {
+			testing.Errorf("Failed test \"%s\" => %v", test.line, t)
+		}
+	}
+
+	for _, line := range []string{"123456789012 too early", "4721105 not a time", "99999999999999999999 too long"} {
+		if t, _, _ := popDatetime(strings.Fields(line)); t != nil {
+			testing.Errorf("Should not parse \"%s\" => %v", line, t)
+		}
+	}
+
+	lines, _ := parseLines(testing, "[    0.000000] Linux version 6.1\n[12345.678901] usb 1-1: new device\n", ParseOptions{})
+	if len(lines) != 2 || !lines[1].Uptime || lines[1].On.Sub(uptimeBase) != 12345678901*time.Microsecond {
+		testing.Errorf("dmesg uptime not parsed: %+v", lines)
+	}
+	if lines[1].Msg != "usb 1-1: new device" || lines[0].Msg != "Linux version 6.1" {
+		testing.Errorf("dmesg message incorrect: %q %q", lines[0].Msg, lines[1].Msg)
+	}
+
+	ll := parseLogLine("ERROR 1700000000 bytes were lost", ParseOptions{}.timeParser(), newLevelMatcher(nil))
+	if ll.On != nil || ll.Src == nil || *ll.Src != "1700000000" || ll.Msg != "bytes were lost" {
+		testing.Errorf("Only a leading number should be a time: %+v", ll)
+	}
+	ll = parseLogLine("- 1713353112 epoch after a dash", ParseOptions{}.timeParser(), newLevelMatcher(nil))
+	if ll.On == nil || !ll.On.Equal(std) {
+		testing.Errorf("Leading special characters should be skipped: %v", ll.On)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Payload is a JSON value found inside a message at Msg[Start:End]. A JSON
+// string holding JSON is decoded and marked as Encoded.
+type Payload struct {
+	Start   int             `json:"start"`
+	End     int             `json:"end"`
+	JSON    json.RawMessage `json:"json"`
+	Encoded bool            `json:"encoded"`
+}
+
+// findPayloads scans the message for every balanced JSON object, array
+// holding strings or objects, and double-encoded JSON string. Plain
+// arrays of numbers are left alone as they are mostly indices like [3].
+func findPayloads(msg string) []Payload {
+	var payloads []Payload
+	for i := 0; i < len(msg); i++ {
+		switch msg[i] {
+		case '{', '[':
+			end := balancedEnd(msg, i)
+			if end < 0 {
+				continue
+			}
+			value := msg[i:end]
+			if !json.Valid([]byte(value)) || (value[0] == '[' && !strings.ContainsAny(value, "{\"")) {
+				continue
+			}
+			payloads = append(payloads, Payload{Start: i, End: end, JSON: json.RawMessage(value)})
+			i = end - 1
+		case '"':
+			end := stringEnd(msg, i)
+			if end < 0 {
+				continue
+			}
+			var asString string
+			if json.Unmarshal([]byte(msg[i:end]), &asString) == nil {
+				inner := strings.TrimSpace(asString)
+				if strings.HasPrefix(inner, "{") || strings.HasPrefix(inner, "[") {
+					if json.Valid([]byte(inner)) {
+						payloads = append(payloads, Payload{Start: i, End: end, JSON: json.RawMessage(inner), Encoded: true})
+					}
+				}
+			}
+			i = end - 1
+		}
+	}
+	return payloads
+}
+
+// balancedEnd returns the index after the bracket closing the one at
+// start, or -1 if it is never closed.
+func balancedEnd(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '"':
+			end := stringEnd(s, i)
+			if end < 0 {
+				return -1
+			}
+			i = end - 1
+		}
+	}
+	return -1
+}
+
+// stringEnd returns the index after the quote closing the string starting
+// at start, or -1 if it is never closed.
+func stringEnd(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
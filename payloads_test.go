@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFindPayloads(testing *testing.T) {
+	msg := `request {"id":1,"tags":["a}"]} took 12ms [main] [1,2] then ["x"] and "{\"ok\":true}" and {broken`
+	payloads := findPayloads(msg)
+	if len(payloads) != 3 {
+		testing.Fatalf("Expected 3 payloads, got %+v", payloads)
+	}
+	expected := []string{`{"id":1,"tags":["a}"]}`, `["x"]`, `"{\"ok\":true}"`}
+	for i, payload := range payloads {
+		if msg[payload.Start:payload.End] != expected[i] {
+			testing.Errorf("Bad span for payload %d: %q", i, msg[payload.Start:payload.End])
+		}
+	}
+	if !payloads[2].Encoded || string(payloads[2].JSON) != `{"ok":true}` {
+		testing.Errorf("Double encoded JSON not decoded: %+v", payloads[2])
+	}
+
+	lines, _ := parseLines(testing, "2022-04-17 11:25:12 INFO got {\"a\":1} and {\"b\":2}\n", ParseOptions{})
+	if len(lines) != 1 || string(lines[0].JSON) != `{"b":2}` || len(lines[0].Payloads) != 1 || string(lines[0].Payloads[0].JSON) != `{"a":1}` {
+		testing.Errorf("Trailing JSON should stay in JSON with the rest in payloads: %+v", lines)
+	}
+}
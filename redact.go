@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Redaction is the per-site setting that hides secrets and personal data
+// before entries are cached or shown. IPs are only hidden if asked for as
+// they are mostly needed to make sense of a log.
+type Redaction struct {
+	Enabled bool `json:"enabled"`
+	IPs     bool `json:"ips"`
+}
+
+type redactRule struct {
+	rx      *regexp.Regexp
+	replace string
+	valid   func(string) bool
+}
+
+const secretNames = `password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|secret[_-]?key|client[_-]?secret|private[_-]?key|credentials?`
+
+// secretKeyRx matches keys that end in a secret name, like db_password or
+// access_token.
+var secretKeyRx *regexp.Regexp = regexp.MustCompile(`(?i)(?:` + secretNames + `|authorization)$`)
+
+var redactRules = []redactRule{
+	{rx: regexp.MustCompile(`(?i)\b((?:proxy-)?authorization["']?\s*[:=]\s*["']?)(?:(bearer|basic|digest|token)\s+)?[^\s"',;{}\[\]]+`), replace: "${1}${2} <TOKEN>"},
+	{rx: regexp.MustCompile(`(?i)\b(bearer)\s+[\w\-.~+/]+=*`), replace: "${1} <TOKEN>"},
+	{rx: regexp.MustCompile(`\beyJ[\w-]+\.eyJ[\w-]+\.[\w-]+`), replace: "<JWT>"},
+	{rx: regexp.MustCompile(`\b([a-z][\w+.-]*://[^:/\s@]+:)[^@/\s]+@`), replace: "${1}<PASSWORD>@"},
+	{rx: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), replace: "<AWS_KEY>"},
+	{rx: regexp.MustCompile(`(?i)\b([\w.-]*(?:` + secretNames + `))(["']?\s*[:=]\s*["']?)[^\s"',;&{}\[\]]+`), replace: "${1}${2}<SECRET>"},
+	{rx: regexp.MustCompile(`\b[\w.+-]+@[\w-]+(?:\.[\w-]+)*\.[A-Za-z]{2,}\b`), replace: "<EMAIL>"},
+	{rx: regexp.MustCompile(`\b[2-6]\d(?:[ -]?\d){11,17}\b`), replace: "<CARD>", valid: luhn},
+}
+
+var ipRules = []redactRule{
+	{rx: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), replace: "<IP>", valid: isIP},
+	{rx: regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}`), replace: "<IP>", valid: isIPv6},
+}
+
+// redactor replaces what the detectors find with typed placeholders like
+// <EMAIL> or <CARD>.
+type redactor struct {
+	rules []redactRule
+}
+
+// newRedactor returns nil if redaction is off for the site.
+func newRedactor(redaction Redaction) *redactor {
+	if !redaction.Enabled {
+		return nil
+	}
+	r := redactor{rules: redactRules}
+	if redaction.IPs {
+		r.rules = append(append([]redactRule(nil), redactRules...), ipRules...)
+	}
+	return &r
+}
+
+func (r *redactor) text(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, rule := range r.rules {
+		if rule.valid == nil {
+			s = rule.rx.ReplaceAllString(s, rule.replace)
+			continue
+		}
+		s = rule.rx.ReplaceAllStringFunc(s, func(m string) string {
+			if !rule.valid(m) {
+				return m
+			}
+			return rule.replace
+		})
+	}
+	return s
+}
+
+// entry redacts every text the entry carries.
+func (r *redactor) entry(ll *LogLine) {
+	if r == nil {
+		return
+	}
+	ll.Msg = r.text(ll.Msg)
+	ll.Raw = r.text(ll.Raw)
+	ll.XML = r.text(ll.XML)
+	if ll.Src != nil {
+		src := r.text(*ll.Src)
+		ll.Src = &src
+	}
+	if len(ll.Stack) > 0 {
+		stack := make([]string, len(ll.Stack))
+		for i, line := range ll.Stack {
+			stack[i] = r.text(line)
+		}
+		ll.Stack = stack
+	}
+	if len(ll.Fields) > 0 {
+		fields := map[string]string{}
+		for key, value := range ll.Fields {
+			if secretKeyRx.MatchString(key) {
+				value = "<SECRET>"
+			}
+			fields[key] = r.text(value)
+		}
+		ll.Fields = fields
+	}
+	if ll.JSON != nil {
+		ll.JSON = r.json(ll.JSON)
+	}
+}
+
+// openEntry redacts an entry whose payload body is still open, keeping
+// the body's start in line with the redacted Msg.
+func (r *redactor) openEntry(ll *LogLine, body *payloadBody) {
+	if r == nil {
+		return
+	}
+	prefix, rest := r.text(ll.Msg[:body.Start]), r.text(ll.Msg[body.Start:])
+	r.entry(ll)
+	ll.Msg = prefix + rest
+	body.Start = len(prefix)
+}
+
+// json redacts the strings of a JSON value, the values of secret keys and
+// card numbers, leaving its layout as it is.
+func (r *redactor) json(raw json.RawMessage) json.RawMessage {
+	s := string(raw)
+	var out strings.Builder
+	secret := false
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			end := stringEnd(s, i)
+			if end < 0 {
+				out.WriteString(s[i:])
+				i = len(s)
+				continue
+			}
+			literal := s[i:end]
+			i = end
+			var str string
+			json.Unmarshal([]byte(literal), &str)
+			if strings.HasPrefix(strings.TrimLeft(s[end:], " \t\r\n"), ":") {
+				secret = secretKeyRx.MatchString(str)
+				out.WriteString(literal)
+				continue
+			}
+			if secret {
+				literal = `"<SECRET>"`
+			} else if redacted := r.text(str); redacted != str {
+				var b strings.Builder
+				encoder := json.NewEncoder(&b)
+				encoder.SetEscapeHTML(false)
+				encoder.Encode(redacted)
+				literal = strings.TrimSuffix(b.String(), "\n")
+			}
+			secret = false
+			out.WriteString(literal)
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(s) && strings.IndexByte("+-.eE0123456789", s[end]) >= 0 {
+				end++
+			}
+			number := s[i:end]
+			i = end
+			if secret {
+				number = `"<SECRET>"`
+			} else if r.text(number) == "<CARD>" {
+				number = `"<CARD>"`
+			}
+			secret = false
+			out.WriteString(number)
+		default:
+			if strings.IndexByte("{}[],", c) >= 0 {
+				secret = false
+			}
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return json.RawMessage(out.String())
+}
+
+func luhn(s string) bool {
+	sum := 0
+	double := false
+	digits := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+func isIP(s string) bool {
+	return net.ParseIP(s) != nil
+}
+
+func isIPv6(s string) bool {
+	return strings.Count(s, ":") >= 2 && net.ParseIP(s) != nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Severity is a level normalised across log formats so that entries can
+// be compared and filtered by how serious they are.
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityTrace
+	SeverityDebug
+	SeverityInfo
+	SeverityNotice
+	SeverityWarn
+	SeverityError
+	SeverityCritical
+	SeverityFatal
+)
+
+var severityNames = []string{"UNKNOWN", "TRACE", "DEBUG", "INFO", "NOTICE", "WARN", "ERROR", "CRITICAL", "FATAL"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return severityNames[SeverityUnknown]
+	}
+	return severityNames[s]
+}
+
+var levelWords = map[string]Severity{
+	"trace":         SeverityTrace,
+	"trc":           SeverityTrace,
+	"finest":        SeverityTrace,
+	"finer":         SeverityTrace,
+	"debug":         SeverityDebug,
+	"dbg":           SeverityDebug,
+	"fine":          SeverityDebug,
+	"verbose":       SeverityDebug,
+	"info":          SeverityInfo,
+	"inf":           SeverityInfo,
+	"information":   SeverityInfo,
+	"informational": SeverityInfo,
+	"notice":        SeverityNotice,
+	"warn":          SeverityWarn,
+	"warning":       SeverityWarn,
+	"wrn":           SeverityWarn,
+	"error":         SeverityError,
+	"err":           SeverityError,
+	"severe":        SeverityError,
+	"crit":          SeverityCritical,
+	"critical":      SeverityCritical,
+	"alert":         SeverityFatal,
+	"emerg":         SeverityFatal,
+	"emergency":     SeverityFatal,
+	"fatal":         SeverityFatal,
+	"ftl":           SeverityFatal,
+	"panic":         SeverityFatal,
+}
+
+// androidLevels are the single letter priorities of logcat's E/Tag lines.
+var androidLevels = map[string]Severity{
+	"v": SeverityTrace,
+	"d": SeverityDebug,
+	"i": SeverityInfo,
+	"w": SeverityWarn,
+	"e": SeverityError,
+	"f": SeverityFatal,
+	"a": SeverityFatal,
+}
+
+var androidLevelRx *regexp.Regexp = regexp.MustCompile(`^([VDIWEFA])/\S`)
+var levelKeyRx *regexp.Regexp = regexp.MustCompile(`^(?i)(?:level|lvl|severity|loglevel)=`)
+
+func ParseSeverity(name string) (Severity, bool) {
+	s, ok := levelWords[strings.ToLower(name)]
+	return s, ok
+}
+
+// levelMatcher finds and normalises levels, including the per-site aliases
+// for vendor specific words.
+type levelMatcher struct {
+	aliases map[string]Severity
+}
+
+func newLevelMatcher(aliases map[string]string) levelMatcher {
+	lm := levelMatcher{aliases: map[string]Severity{}}
+	for word, level := range aliases {
+		if s, ok := ParseSeverity(level); ok {
+			lm.aliases[strings.ToLower(word)] = s
+		}
+	}
+	return lm
+}
+
+func validateLevelAliases(aliases map[string]string) error {
+	for word, level := range aliases {
+		if _, ok := ParseSeverity(level); !ok {
+			return fmt.Errorf("level alias %s maps to unknown level %s", word, level)
+		}
+	}
+	return nil
+}
+
+// cleanLevel strips the decoration levels often come with: [ERROR],
+// (warn), ERROR:, level=error.
+func cleanLevel(token string) string {
+	token = levelKeyRx.ReplaceAllString(token, "")
+	token = strings.TrimSuffix(token, ":")
+	if len(token) > 2 && (token[0] == '[' && token[len(token)-1] == ']' || token[0] == '(' && token[len(token)-1] == ')') {
+		token = token[1 : len(token)-1]
+	}
+	return strings.Trim(token, `"`)
+}
+
+func (lm levelMatcher) lookup(word string) (Severity, bool) {
+	lower := strings.ToLower(word)
+	if s, ok := lm.aliases[lower]; ok {
+		return s, true
+	}
+	s, ok := levelWords[lower]
+	return s, ok
+}
+
+func (lm levelMatcher) severity(level *string) Severity {
+	if level == nil {
+		return SeverityUnknown
+	}
+	if s, ok := lm.lookup(cleanLevel(*level)); ok {
+		return s
+	}
+	if s, ok := androidLevels[strings.ToLower(*level)]; ok {
+		return s
+	}
+	return SeverityUnknown
+}
+
+// matchLevel checks if token is a level. The first token after the time
+// may be in any case or decoration; further along only upper case words
+// count so that ordinary words in a message are not taken as levels. With
+// no time before it a mixed case word must be decorated ([error], error:)
+// as "Error connecting to db" is just a message.
+// Android E/Tag tokens return the tag as rest.
+func (lm levelMatcher) matchLevel(token string, first bool, timed bool) (level string, rest string, ok bool) {
+	if m := androidLevelRx.FindStringSubmatch(token); m != nil && first {
+		return m[1], token[2:], true
+	}
+	level = cleanLevel(token)
+	if level != strings.ToUpper(level) && !(first && (timed || level != token)) {
+		return "", "", false
+	}
+	if _, ok := lm.lookup(level); !ok {
+		return "", "", false
+	}
+	return level, "", true
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLevelParsing(testing *testing.T) {
+	tests := []struct {
+		line     string
+		level    string
+		severity Severity
+		msg      string
+	}{
+		{"2022-04-17 11:25:12 FATAL out of memory", "FATAL", SeverityFatal, "out of memory"},
+		{"2022-04-17 11:25:12 warning: disk low", "warning", SeverityWarn, "disk low"},
+		{"2022-04-17 11:25:12 [ERROR] write failed", "ERROR", SeverityError, "write failed"},
+		{"2022-04-17 11:25:12 level=error write failed", "error", SeverityError, "write failed"},
+		{"2022-04-17 11:25:12 [main] SEVERE bad things", "SEVERE", SeverityError, "bad things"},
+		{"2022-04-17 11:25:12 NOTICE rotated", "NOTICE", SeverityNotice, "rotated"},
+		{"2022-04-17 11:25:12 E/ActivityManager(1234): ANR in app", "E", SeverityError, "ActivityManager(1234): ANR in app"},
+		{"2022-04-17 11:25:12 some info here", "", SeverityUnknown, "some info here"},
+		{"2022-04-17 11:25:12 OOPS vendor word", "OOPS", SeverityCritical, "vendor word"},
+		{"Error connecting to db: timeout", "", SeverityUnknown, "Error connecting to db: timeout"},
+		{"Info about the thing", "", SeverityUnknown, "Info about the thing"},
+		{"[error] connecting to db", "error", SeverityError, "connecting to db"},
+		{"Error: connecting to db", "Error", SeverityError, "connecting to db"},
+		{"ERROR connecting to db", "ERROR", SeverityError, "connecting to db"},
+	}
+
+	lm := newLevelMatcher(map[string]string{"oops": "critical"})
+	for _, test := range tests {
+		ll := parseLogLine(test.line, ParseOptions{}.timeParser(), lm)
+		level := ""
+		if ll.Level != nil {
+			level = *ll.Level
+		}
+		if level != test.level || lm.severity(ll.Level) != test.severity || ll.Msg != test.msg {
+			testing.Errorf("Failed test \"%s\" => %q %v %q", test.line, level, lm.severity(ll.Level), ll.Msg)
+		}
+	}
+
+	ll := parseLogLine("2022-04-17 11:25:12 [ERROR] write failed", ParseOptions{}.timeParser(), lm)
+	if ll.LevelRaw == nil || *ll.LevelRaw != "[ERROR]" || *ll.Level != "ERROR" {
+		testing.Errorf("Raw level should be kept as written: %+v", ll)
+	}
+
+	if SeverityWarn <= SeverityInfo || SeverityFatal <= SeverityError {
+		testing.Error("Severities out of order")
+	}
+	if err := validateLevelAliases(map[string]string{"oops": "bad"}); err == nil {
+		testing.Error("Alias to unknown level should not validate")
+	}
+}
+
+func TestSeverityAcrossFormats(testing *testing.T) {
+	lines, _ := parseLines(testing, `{"level":"warning","msg":"a"}`+"\n"+`{"level":"crit","msg":"b"}`+"\n", ParseOptions{})
+	if len(lines) != 2 || lines[0].Severity != SeverityWarn || lines[1].Severity != SeverityCritical {
+		testing.Errorf("Severity not normalised: %+v", lines)
+	}
+}
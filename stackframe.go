@@ -0,0 +1,169 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// StackFrame is one parsed frame of a stack trace. IsCaused marks frames
+// of a cause (Java "Caused by:", a chained Python traceback or a .NET
+// inner exception) rather than of the exception that was reported, and
+// IsApp marks frames in the site's own packages.
+type StackFrame struct {
+	Func     string `json:"func"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Module   string `json:"module"`
+	IsCaused bool   `json:"caused"`
+	IsApp    bool   `json:"app"`
+	Raw      string `json:"raw"`
+}
+
+var javaFrameRx *regexp.Regexp = regexp.MustCompile(`^\s*at ([\w$.<>/-]+)\.([\w$<>-]+)\(([\w$.-]+(?::(\d+))?|Native Method|Unknown Source)\)`)
+var nodeFrameRx *regexp.Regexp = regexp.MustCompile(`^\s*at (?:(.+?) \()?([^\s()]+):(\d+):\d+\)?$`)
+var dotnetFrameRx *regexp.Regexp = regexp.MustCompile(`^\s*at ([\w.<>` + "`" + `+\[\],]+)\((.*?)\)(?: in (.+):line (\d+))?\s*$`)
+var pythonFrameRx *regexp.Regexp = regexp.MustCompile(`^\s*File "([^"]+)", line (\d+)(?:, in (.+))?$`)
+var goFuncRx *regexp.Regexp = regexp.MustCompile(`^(?:created by )?(\S+?)(?:\([^()]*\))?(?: in goroutine \d+)?$`)
+var goFileRx *regexp.Regexp = regexp.MustCompile(`^\s+(\S+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+
+var javaCausedRx *regexp.Regexp = regexp.MustCompile(`^\s*Caused by:`)
+var pythonCauseRx *regexp.Regexp = regexp.MustCompile(`^\s*(The above exception was the direct cause|During handling of the above exception)`)
+var dotnetInnerEndRx *regexp.Regexp = regexp.MustCompile(`^\s*--- End of inner exception stack trace ---`)
+
+func parseStackFrames(stack []string, appPackages []string) []StackFrame {
+	frames := []StackFrame{}
+	caused := false
+	causeStart := 0
+	for i := 0; i < len(stack); i++ {
+		line := stack[i]
+
+		if javaCausedRx.MatchString(line) {
+			caused = true
+			continue
+		}
+		if pythonCauseRx.MatchString(line) || dotnetInnerEndRx.MatchString(line) {
+			// the frames so far were the cause of what follows
+			for j := causeStart; j < len(frames); j++ {
+				frames[j].IsCaused = true
+			}
+			causeStart = len(frames)
+			continue
+		}
+
+		frame, ok := parseStackFrame(line)
+		if !ok && i+1 < len(stack) {
+			frame, ok = parseGoFrame(line, stack[i+1])
+			if ok {
+				i++
+			}
+		}
+		if !ok {
+			continue
+		}
+		frame.IsCaused = caused
+		frame.IsApp = isAppFrame(frame, appPackages)
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func parseStackFrame(line string) (StackFrame, bool) {
+	frame := StackFrame{Raw: line}
+
+	if m := javaFrameRx.FindStringSubmatch(line); m != nil {
+		class := m[1]
+		if slash := strings.LastIndex(class, "/"); slash >= 0 {
+			class = class[slash+1:]
+		}
+		frame.Func = class + "." + m[2]
+		frame.File = strings.SplitN(m[3], ":", 2)[0]
+		frame.Line, _ = strconv.Atoi(m[4])
+		if dot := strings.LastIndex(class, "."); dot >= 0 {
+			frame.Module = class[:dot]
+		}
+		return frame, true
+	}
+
+	if m := nodeFrameRx.FindStringSubmatch(line); m != nil {
+		frame.Func = strings.TrimPrefix(m[1], "async ")
+		frame.File = m[2]
+		frame.Line, _ = strconv.Atoi(m[3])
+		frame.Module = m[2]
+		return frame, true
+	}
+
+	if m := dotnetFrameRx.FindStringSubmatch(line); m != nil {
+		frame.Func = m[1]
+		frame.File = m[3]
+		frame.Line, _ = strconv.Atoi(m[4])
+		if dot := strings.LastIndex(m[1], "."); dot >= 0 {
+			if dot = strings.LastIndex(m[1][:dot], "."); dot >= 0 {
+				frame.Module = m[1][:dot]
+			}
+		}
+		return frame, true
+	}
+
+	if m := pythonFrameRx.FindStringSubmatch(line); m != nil {
+		frame.File = m[1]
+		frame.Line, _ = strconv.Atoi(m[2])
+		frame.Func = m[3]
+		frame.Module = pythonModule(m[1])
+		return frame, true
+	}
+
+	return frame, false
+}
+
+// parseGoFrame reads the two lines Go prints for each frame: the function
+// and then, indented, the file and line.
+func parseGoFrame(funcLine string, fileLine string) (StackFrame, bool) {
+	fm := goFileRx.FindStringSubmatch(fileLine)
+	if fm == nil {
+		return StackFrame{}, false
+	}
+	m := goFuncRx.FindStringSubmatch(funcLine)
+	if m == nil {
+		return StackFrame{}, false
+	}
+	frame := StackFrame{
+		Func: m[1],
+		File: fm[1],
+		Raw:  funcLine + "\n" + fileLine,
+	}
+	frame.Line, _ = strconv.Atoi(fm[2])
+	frame.Module = goPackage(m[1])
+	return frame, true
+}
+
+// goPackage is the import path of a Go function name such as
+// github.com/x/y.(*T).Method.
+func goPackage(fn string) string {
+	slash := strings.LastIndex(fn, "/")
+	dot := strings.Index(fn[slash+1:], ".")
+	if dot < 0 {
+		return fn
+	}
+	return fn[:slash+1+dot]
+}
+
+func pythonModule(file string) string {
+	file = strings.TrimSuffix(file, ".py")
+	if slash := strings.LastIndexAny(file, `/\`); slash >= 0 {
+		file = file[slash+1:]
+	}
+	return file
+}
+
+func isAppFrame(frame StackFrame, appPackages []string) bool {
+	for _, pkg := range appPackages {
+		if pkg == "" {
+			continue
+		}
+		if strings.HasPrefix(frame.Module, pkg) || strings.HasPrefix(frame.Func, pkg) || strings.HasPrefix(frame.File, pkg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseStackFrames(testing *testing.T) {
+	tests := []struct {
+		name   string
+		stack  []string
+		frames []StackFrame
+	}{
+		{"java", []string{
+			"java.lang.IllegalStateException: boom",
+			"\tat com.acme.Service.run(Service.java:12)",
+			"\tat java.base/java.lang.Thread.run(Thread.java:833)",
+			"Caused by: java.io.IOException: closed",
+			"\tat com.acme.Reader.read(Reader.java:40)",
+			"\t... 2 more",
+		}, []StackFrame{
+			{Func: "com.acme.Service.run", File: "Service.java", Line: 12, Module: "com.acme", IsApp: true},
+			{Func: "java.lang.Thread.run", File: "Thread.java", Line: 833, Module: "java.lang"},
+			{Func: "com.acme.Reader.read", File: "Reader.java", Line: 40, Module: "com.acme", IsCaused: true, IsApp: true},
+		}},
+		{"python", []string{
+			"Traceback (most recent call last):",
+			`  File "/srv/acme/db.py", line 10, in connect`,
+			"    raise IOError()",
+			"OSError",
+			"",
+			"The above exception was the direct cause of the following exception:",
+			"",
+			"Traceback (most recent call last):",
+			`  File "/srv/acme/app.py", line 3, in <module>`,
+		}, []StackFrame{
+			{Func: "connect", File: "/srv/acme/db.py", Line: 10, Module: "db", IsCaused: true, IsApp: true},
+			{Func: "<module>", File: "/srv/acme/app.py", Line: 3, Module: "app", IsApp: true},
+		}},
+		{"go", []string{
+			"goroutine 1 [running]:",
+			"github.com/acme/svc.(*Server).Serve(0xc000010000)",
+			"\t/src/svc/server.go:42 +0x1d",
+			"main.main()",
+			"\t/src/main.go:9 +0x25",
+		}, []StackFrame{
+			{Func: "github.com/acme/svc.(*Server).Serve", File: "/src/svc/server.go", Line: 42, Module: "github.com/acme/svc", IsApp: true},
+			{Func: "main.main", File: "/src/main.go", Line: 9, Module: "main"},
+		}},
+		{"node", []string{
+			"TypeError: x is undefined",
+			"    at handle (/srv/acme/index.js:5:11)",
+			"    at node:internal/process/task_queues:95:5",
+		}, []StackFrame{
+			{Func: "handle", File: "/srv/acme/index.js", Line: 5, Module: "/srv/acme/index.js", IsApp: true},
+			{Func: "", File: "node:internal/process/task_queues", Line: 95, Module: "node:internal/process/task_queues"},
+		}},
+		{"dotnet", []string{
+			"System.InvalidOperationException: outer ---> System.Exception: inner",
+			"   at Acme.Data.Repo.Load(Int32 id) in C:\\src\\Repo.cs:line 20",
+			"   --- End of inner exception stack trace ---",
+			"   at Acme.Web.Controller.Get()",
+		}, []StackFrame{
+			{Func: "Acme.Data.Repo.Load", File: "C:\\src\\Repo.cs", Line: 20, Module: "Acme.Data", IsCaused: true, IsApp: true},
+			{Func: "Acme.Web.Controller.Get", Module: "Acme.Web", IsApp: true},
+		}},
+	}
+
+	apps := []string{"com.acme", "/srv/acme", "github.com/acme", "Acme."}
+	for _, test := range tests {
+		frames := parseStackFrames(test.stack, apps)
+		if len(frames) != len(test.frames) {
+			testing.Errorf("%s: expected %d frames, got %d: %+v", test.name, len(test.frames), len(frames), frames)
+			continue
+		}
+		for i, frame := range frames {
+			frame.Raw = ""
+			if frame != test.frames[i] {
+				testing.Errorf("%s: frame %d incorrect: %+v", test.name, i, frame)
+			}
+		}
+	}
+}
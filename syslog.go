@@ -0,0 +1,195 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterLogFormat(newSyslogFormat)
+}
+
+// syslogFormat reads RFC 5424 and RFC 3164 (BSD) syslog lines, with or
+// without the <PRI> prefix that syslog daemons usually strip when writing
+// to files.
+type syslogFormat struct {
+	loc *time.Location
+}
+
+func newSyslogFormat(opts ParseOptions) LogFormat {
+	return syslogFormat{loc: opts.location()}
+}
+
+var rfc5424Rx *regexp.Regexp = regexp.MustCompile(`^<(\d{1,3})>(\d{1,2}) (\S+) (\S+) (\S+) (\S+) (\S+) (.*)$`)
+var rfc3164Rx *regexp.Regexp = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}|\d{4}-\d{2}-\d{2}T\S+) (\S+) ([^\s:\[]+)(?:\[([^\]]*)\])?: ?(.*)$`)
+
+var syslogSeverities = []string{"EMERG", "ALERT", "CRIT", "ERROR", "WARN", "NOTICE", "INFO", "DEBUG"}
+
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+func (f syslogFormat) Name() string {
+	return "syslog"
+}
+
+func (f syslogFormat) Detect(sample []string) float64 {
+	if len(sample) == 0 {
+		return 0
+	}
+	count := 0
+	for _, line := range sample {
+		if rfc5424Rx.MatchString(line) || rfc3164Rx.MatchString(line) {
+			count++
+		}
+	}
+	return float64(count) / float64(len(sample))
+}
+
+func (f syslogFormat) ParseLine(line string) LogLine {
+	if m := rfc5424Rx.FindStringSubmatch(line); m != nil {
+		if ll, ok := parseRFC5424(line, m); ok {
+			return ll
+		}
+	}
+	if m := rfc3164Rx.FindStringSubmatch(line); m != nil {
+		return parseRFC3164(line, m, f.loc)
+	}
+	return LogLine{Raw: line}
+}
+
+func (f syslogFormat) IsContinuation(ll LogLine, open *LogLine) bool {
+	return ll.Fields == nil
+}
+
+func parseRFC5424(line string, m []string) (LogLine, bool) {
+	ll := LogLine{Raw: line, Fields: map[string]string{}}
+	setSyslogPriority(&ll, m[1])
+
+	if m[3] != "-" {
+		t, err := time.Parse(time.RFC3339Nano, m[3])
+		if err != nil {
+			return ll, false
+		}
+		ll.On = &t
+	}
+
+	src := syslogSource(m[4], m[5], m[6])
+	if src != "" {
+		ll.Src = &src
+	}
+	if m[7] != "-" {
+		ll.Fields["msgid"] = m[7]
+	}
+
+	rest := m[8]
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else if strings.HasPrefix(rest, "[") {
+		var ok bool
+		rest, ok = parseStructuredData(rest, ll.Fields)
+		if !ok {
+			return ll, false
+		}
+	} else {
+		return ll, false
+	}
+	rest = strings.TrimPrefix(rest, " ")
+	ll.Msg = strings.TrimPrefix(rest, "\uFEFF")
+	return ll, true
+}
+
+func parseRFC3164(line string, m []string, loc *time.Location) LogLine {
+	ll := LogLine{Raw: line, Fields: map[string]string{}}
+	if m[1] != "" {
+		setSyslogPriority(&ll, m[1])
+	}
+
+	if strings.Contains(m[2], "T") {
+		if t, err := time.Parse(time.RFC3339Nano, m[2]); err == nil {
+			ll.On = &t
+		}
+	} else if t, err := time.ParseInLocation(time.Stamp, m[2], loc); err == nil {
+		t = time.Date(time.Now().Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		ll.On = &t
+	}
+
+	src := syslogSource(m[3], m[4], m[5])
+	ll.Src = &src
+	ll.Msg = m[6]
+	return ll
+}
+
+func setSyslogPriority(ll *LogLine, pri string) {
+	n, err := strconv.Atoi(pri)
+	if err != nil || n > 191 {
+		return
+	}
+	level := syslogSeverities[n%8]
+	ll.Level = &level
+	ll.Fields["facility"] = syslogFacilities[n/8]
+}
+
+func syslogSource(host, app, pid string) string {
+	src := []string{}
+	if host != "" && host != "-" {
+		src = append(src, host)
+	}
+	if app != "" && app != "-" {
+		if pid != "" && pid != "-" {
+			app += "[" + pid + "]"
+		}
+		src = append(src, app)
+	}
+	return strings.Join(src, " ")
+}
+
+// parseStructuredData reads RFC 5424 [id key="value" ...] elements into
+// fields as "id.key" and returns what follows them.
+func parseStructuredData(sd string, fields map[string]string) (string, bool) {
+	for strings.HasPrefix(sd, "[") {
+		end := strings.IndexAny(sd, " ]")
+		if end < 0 {
+			return sd, false
+		}
+		id := sd[1:end]
+		sd = sd[end:]
+		for strings.HasPrefix(sd, " ") {
+			sd = sd[1:]
+			eq := strings.Index(sd, "=\"")
+			if eq < 0 {
+				return sd, false
+			}
+			name := sd[:eq]
+			sd = sd[eq+2:]
+
+			var value strings.Builder
+			closed := false
+			for i := 0; i < len(sd); i++ {
+				if sd[i] == '\\' && i+1 < len(sd) && strings.IndexByte(`"\]`, sd[i+1]) >= 0 {
+					i++
+					value.WriteByte(sd[i])
+				} else if sd[i] == '"' {
+					sd = sd[i+1:]
+					closed = true
+					break
+				} else {
+					value.WriteByte(sd[i])
+				}
+			}
+			if !closed {
+				return sd, false
+			}
+			fields[id+"."+name] = value.String()
+		}
+		if !strings.HasPrefix(sd, "]") {
+			return sd, false
+		}
+		sd = sd[1:]
+	}
+	return sd, true
+}
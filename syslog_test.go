@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRFC5424(testing *testing.T) {
+	f := newSyslogFormat(ParseOptions{})
+	line := `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 8710 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication" eventID="1011"][examplePriority@32473 class="high"] An application event`
+	ll := f.ParseLine(line)
+
+	std := time.Date(2003, 10, 11, 22, 14, 15, 3*1e6, time.UTC)
+	if ll.On == nil || !ll.On.Equal(std) {
+		testing.Errorf("Time not parsed: %v", ll.On)
+	}
+	if ll.Level == nil || *ll.Level != "NOTICE" || ll.Fields["facility"] != "local4" {
+		testing.Errorf("Priority not decoded: %v %v", ll.Level, ll.Fields)
+	}
+	if ll.Src == nil || *ll.Src != "mymachine.example.com evntslog[8710]" {
+		testing.Errorf("Source incorrect: %v", ll.Src)
+	}
+	if ll.Msg != "An application event" {
+		testing.Errorf("Message incorrect: %q", ll.Msg)
+	}
+	if ll.Fields["msgid"] != "ID47" || ll.Fields["exampleSDID@32473.eventSource"] != `App"lication` || ll.Fields["examplePriority@32473.class"] != "high" {
+		testing.Errorf("Structured data incorrect: %v", ll.Fields)
+	}
+
+	ll = f.ParseLine(`<34>1 - - su - - - nil values`)
+	if ll.Fields == nil || ll.Level == nil || *ll.Level != "CRIT" || ll.On != nil || ll.Msg != "nil values" {
+		testing.Errorf("Nil values not handled: %+v", ll)
+	}
+}
+
+func TestRFC3164(testing *testing.T) {
+	data := `<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8
+Apr  7 01:02:03 web-1 sshd[4242]: Accepted publickey for deploy
+  continued line
+2022-04-17T11:25:12.345+02:00 db-2 kernel: Out of memory
+`
+	lines, state := parseLines(testing, data, ParseOptions{})
+	if state.Format != "syslog" {
+		testing.Fatalf("Expected syslog format, got %s", state.Format)
+	}
+	if len(lines) != 3 {
+		testing.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+	if lines[0].Level == nil || *lines[0].Level != "CRIT" || lines[0].Fields["facility"] != "auth" || *lines[0].Src != "mymachine su" {
+		testing.Errorf("First line incorrect: %+v", lines[0])
+	}
+	if lines[1].On == nil || lines[1].On.Day() != 7 || *lines[1].Src != "web-1 sshd[4242]" || lines[1].Msg != "Accepted publickey for deploy\n  continued line" {
+		testing.Errorf("Second line incorrect: %+v", lines[1])
+	}
+	if lines[2].On == nil || *lines[2].Src != "db-2 kernel" || lines[2].Msg != "Out of memory" {
+		testing.Errorf("Third line incorrect: %+v", lines[2])
+	}
+}
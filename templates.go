@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Template is a message pattern mined from the log lines with the variable
+// parts (numbers, ids, addresses...) replaced by slots like <NUM> or <*>.
+type Template struct {
+	ID      int        `json:"id"`
+	Pattern string     `json:"pattern"`
+	Count   int        `json:"count"`
+	First   *time.Time `json:"first"`
+	Last    *time.Time `json:"last"`
+	Sample  string     `json:"sample"`
+}
+
+// templateSimilarity is how many of the tokens need to match for a message
+// to join a template.
+const templateSimilarity = 0.5
+
+const wildcard = "<*>"
+
+var templateMasks = []struct {
+	rx   *regexp.Regexp
+	slot string
+}{
+	{regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`), "<UUID>"},
+	{regexp.MustCompile(`\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}(:\d+)?`), "<IP>"},
+	{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]*\d[0-9a-f]*[a-f][0-9a-f]*\b|\b[0-9a-f]*[a-f][0-9a-f]*\d[0-9a-f]*\b`), "<HEX>"},
+	{regexp.MustCompile(`[-+]?\d+(\.\d+)?`), "<NUM>"},
+}
+
+var templatesMu sync.Mutex
+
+type templateStats struct {
+	Count  int        `json:"count"`
+	First  *time.Time `json:"first"`
+	Last   *time.Time `json:"last"`
+	Sample string     `json:"sample"`
+}
+
+type minedTemplate struct {
+	ID     int                       `json:"id"`
+	Tokens []string                  `json:"tokens"`
+	Files  map[string]*templateStats `json:"files"`
+}
+
+// templateMiner groups messages into templates the way Drain does: by
+// token count and first token, then by the share of matching tokens. It
+// is kept per site so template ids are the same in every log of the site.
+type templateMiner struct {
+	Templates []*minedTemplate `json:"templates"`
+	groups    map[string][]*minedTemplate
+}
+
+func newTemplateMiner() *templateMiner {
+	return &templateMiner{groups: map[string][]*minedTemplate{}}
+}
+
+func loadTemplateMiner(path string) *templateMiner {
+	m := newTemplateMiner()
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, m) != nil {
+		return newTemplateMiner()
+	}
+	for _, t := range m.Templates {
+		key := templateKey(t.Tokens)
+		m.groups[key] = append(m.groups[key], t)
+	}
+	return m
+}
+
+func (m *templateMiner) save(path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// mine sets the template of the line, adding it to the counts of file if
+// count is set.
+func (m *templateMiner) mine(file string, ll *LogLine, count bool) {
+	msg := ll.Msg
+	if msg == "" {
+		msg = ll.Raw
+	}
+	msg, _, _ = strings.Cut(msg, "\n")
+	tokens := templateTokens(msg)
+	if len(tokens) == 0 {
+		return
+	}
+
+	t := m.add(tokens)
+	ll.TemplateID = t.ID
+	if !count {
+		return
+	}
+	stats := t.Files[file]
+	if stats == nil {
+		stats = &templateStats{Sample: ll.Raw}
+		t.Files[file] = stats
+	}
+	stats.Count++
+	if ll.On != nil && !ll.Uptime {
+		if stats.First == nil || ll.On.Before(*stats.First) {
+			stats.First = ll.On
+		}
+		if stats.Last == nil || ll.On.After(*stats.Last) {
+			stats.Last = ll.On
+		}
+	}
+}
+
+func (m *templateMiner) add(tokens []string) *minedTemplate {
+	key := templateKey(tokens)
+	var best *minedTemplate
+	bestScore := 0.0
+	for _, t := range m.groups[key] {
+		score := templateScore(t.Tokens, tokens)
+		if score > bestScore {
+			best, bestScore = t, score
+		}
+	}
+
+	if best == nil || bestScore < templateSimilarity {
+		best = &minedTemplate{ID: len(m.Templates) + 1, Tokens: tokens, Files: map[string]*templateStats{}}
+		m.Templates = append(m.Templates, best)
+		m.groups[key] = append(m.groups[key], best)
+		return best
+	}
+	for i, token := range tokens {
+		if best.Tokens[i] != token {
+			best.Tokens[i] = wildcard
+		}
+	}
+	return best
+}
+
+// reset forgets the counts of file, before it is parsed again in full.
+func (m *templateMiner) reset(file string) {
+	for _, t := range m.Templates {
+		delete(t.Files, file)
+	}
+}
+
+// templates returns the templates seen in file, or in all files if file
+// is empty, most frequent first.
+func (m *templateMiner) templates(file string) []Template {
+	templates := []Template{}
+	for _, t := range m.Templates {
+		template := Template{ID: t.ID, Pattern: strings.Join(t.Tokens, " ")}
+		for name, stats := range t.Files {
+			if file != "" && name != file {
+				continue
+			}
+			if template.Count == 0 {
+				template.Sample = stats.Sample
+			}
+			template.Count += stats.Count
+			if stats.First != nil && (template.First == nil || stats.First.Before(*template.First)) {
+				template.First = stats.First
+			}
+			if stats.Last != nil && (template.Last == nil || stats.Last.After(*template.Last)) {
+				template.Last = stats.Last
+			}
+		}
+		if template.Count > 0 {
+			templates = append(templates, template)
+		}
+	}
+	sort.SliceStable(templates, func(i, j int) bool {
+		return templates[i].Count > templates[j].Count
+	})
+	return templates
+}
+
+func templateTokens(msg string) []string {
+	tokens := strings.Fields(msg)
+	for i, token := range tokens {
+		for _, mask := range templateMasks {
+			token = mask.rx.ReplaceAllString(token, mask.slot)
+		}
+		tokens[i] = token
+	}
+	return tokens
+}
+
+// templateKey is the Drain tree path: token count and the first token,
+// unless that one is variable.
+func templateKey(tokens []string) string {
+	first := tokens[0]
+	if strings.Contains(first, "<") {
+		first = wildcard
+	}
+	return fmt.Sprintf("%d %s", len(tokens), first)
+}
+
+func templateScore(template []string, tokens []string) float64 {
+	same := 0
+	for i, token := range template {
+		if token == tokens[i] || token == wildcard {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens))
+}
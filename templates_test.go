@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateMiner(testing *testing.T) {
+	data := `2022-04-17 11:25:12 INFO user 42 logged in from 10.0.0.1
+2022-04-17 11:25:13 INFO user 7 logged in from 10.0.0.2:8080
+2022-04-17 11:25:14 INFO request 3f2504e0-4f89-11d3-9a0c-0305e82c3301 took 12ms
+2022-04-17 11:25:15 INFO user bob logged in from 10.0.0.3
+2022-04-17 11:25:16 WARN cache miss for 0xdeadbeef
+`
+	lines, _ := parseLines(testing, data, ParseOptions{})
+	miner := newTemplateMiner()
+	for i := range lines {
+		miner.mine("a.log", &lines[i], true)
+	}
+	if lines[0].TemplateID != lines[1].TemplateID || lines[0].TemplateID != lines[3].TemplateID || lines[0].TemplateID == lines[2].TemplateID {
+		testing.Errorf("Lines not grouped into templates: %+v", lines)
+	}
+
+	templates := miner.templates("")
+	if len(templates) != 3 {
+		testing.Fatalf("Expected 3 templates, got %+v", templates)
+	}
+	top := templates[0]
+	if top.Pattern != "user <*> logged in from <IP>" || top.Count != 3 || top.First.Second() != 12 || top.Last.Second() != 15 || top.Sample != lines[0].Raw {
+		testing.Errorf("Bad top template: %+v", top)
+	}
+	if !strings.Contains(templates[1].Pattern+templates[2].Pattern, "request <UUID> took <NUM>ms") || !strings.Contains(templates[1].Pattern+templates[2].Pattern, "cache miss for <HEX>") {
+		testing.Errorf("Variables not masked: %+v", templates)
+	}
+
+	path := filepath.Join(testing.TempDir(), "templates.json")
+	if err := miner.save(path); err != nil {
+		testing.Fatal(err)
+	}
+	miner = loadTemplateMiner(path)
+	other := LogLine{Msg: "user 9 logged in from 10.0.0.9", Raw: "user 9 logged in from 10.0.0.9"}
+	miner.mine("b.log", &other, true)
+	if other.TemplateID != lines[0].TemplateID {
+		testing.Errorf("Site miner should reuse the template across files: %+v", other)
+	}
+	if len(miner.templates("b.log")) != 1 || miner.templates("")[0].Count != 4 {
+		testing.Errorf("Bad per file counts: %+v", miner.templates(""))
+	}
+
+	miner.reset("a.log")
+	if len(miner.templates("")) != 1 {
+		testing.Errorf("Reset should forget the counts of the file: %+v", miner.templates(""))
+	}
+}
+
+func TestCountedEntries(testing *testing.T) {
+	logfile := filepath.Join(testing.TempDir(), "growing.log")
+	if err := os.WriteFile(logfile, []byte("2022-04-17 11:25:12 INFO one\n2022-04-17 11:25:13 INFO tw"), 0644); err != nil {
+		testing.Fatal(err)
+	}
+	log, state, err := parseLogFile(logfile, ParseOptions{}, nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	count, counted := countedEntries(log.Lines, 0, nil, state)
+	if len(log.Lines) != 2 || len(count) != 0 || counted != 0 {
+		testing.Fatalf("Pending entries and the partial line should wait: %v %d", count, counted)
+	}
+
+	if err := os.WriteFile(logfile, []byte("2022-04-17 11:25:12 INFO one\n2022-04-17 11:25:13 INFO two\n2022-04-17 11:25:14 INFO three\n"), 0644); err != nil {
+		testing.Fatal(err)
+	}
+	wasPending := state.Pending
+	update, state, err := parseLogFile(logfile, ParseOptions{}, state)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	count, counted = countedEntries(update.Lines, counted, wasPending, state)
+	if len(count) != 2 || !count[1] || !count[2] || counted != 2 {
+		testing.Errorf("Entries should be counted once closed: %v %d", count, counted)
+	}
+
+	if err := os.WriteFile(logfile, []byte("2022-04-17 11:25:12 ERROR request failed\n"), 0644); err != nil {
+		testing.Fatal(err)
+	}
+	log, state, err = parseLogFile(logfile, ParseOptions{}, nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	count, counted = countedEntries(log.Lines, 0, nil, state)
+	if err := os.WriteFile(logfile, []byte("2022-04-17 11:25:12 ERROR request failed\njava.lang.NullPointerException\n\tat com.acme.Api.call(Api.java:10)\n2022-04-17 11:25:13 INFO next\n"), 0644); err != nil {
+		testing.Fatal(err)
+	}
+	wasPending = state.Pending
+	update, state, err = parseLogFile(logfile, ParseOptions{}, state)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	count, _ = countedEntries(update.Lines, counted, wasPending, state)
+	if !count[1] || update.Lines[0].Num != 1 || len(update.Lines[0].Stack) == 0 {
+		testing.Errorf("An entry should be counted once its stack is in: %v %+v", count, update.Lines)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// threadWindow is how many entries are held back waiting for lines from
+// interleaved threads.
+const threadWindow = 16
+
+var causedByRx *regexp.Regexp = regexp.MustCompile(`^\s*(Caused by|Suppressed):`)
+var frameLineRx *regexp.Regexp = regexp.MustCompile(`^\s+at\s|^\s*\.\.\. \d+ (more|common frames omitted)\s*$`)
+var moreRx *regexp.Regexp = regexp.MustCompile(`^\s*\.\.\. \d+ (more|common frames omitted)\s*$`)
+
+// threadOf returns the [thread] token from the entry's source, if any.
+func threadOf(ll *LogLine) string {
+	if thread, ok := ll.Fields["thread"]; ok {
+		return thread
+	}
+	if ll.Src == nil {
+		return ""
+	}
+	for _, token := range strings.Fields(*ll.Src) {
+		if len(token) > 2 && strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]") {
+			return token
+		}
+	}
+	return ""
+}
+
+// closed reports if the pending entry at i can take no more lines because
+// a later entry came from the same thread.
+func (p *logParser) closed(i int) bool {
+	thread := threadOf(p.pending[i])
+	if thread == "" {
+		return true
+	}
+	for _, ll := range p.pending[i+1:] {
+		if threadOf(ll) == thread {
+			return true
+		}
+	}
+	return false
+}
+
+// continuationTarget picks the entry a continuation line belongs to. Stack
+// frames go to the latest entry whose trace is still being written, and a
+// "Caused by:" can also follow a trace ended by "... N more". Everything
+// else goes to the latest entry.
+func (p *logParser) continuationTarget(line string) *LogLine {
+	if len(p.pending) < 2 || threadOf(p.open) == "" {
+		return p.open
+	}
+
+	cause := causedByRx.MatchString(line)
+	if !cause && !frameLineRx.MatchString(line) {
+		return p.open
+	}
+
+	for i := len(p.pending) - 1; i >= 0; i-- {
+		ll := p.pending[i]
+		if len(ll.Stack) == 0 || p.closed(i) {
+			continue
+		}
+		afterMore := moreRx.MatchString(ll.Stack[len(ll.Stack)-1])
+		if cause || !afterMore {
+			return ll
+		}
+	}
+	return p.open
+}
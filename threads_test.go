@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestThreadInterleaving(testing *testing.T) {
+	data := `2022-04-17 11:25:12 ERROR [worker-1] request failed
+java.lang.IllegalStateException: bad state
+2022-04-17 11:25:12 INFO [worker-2] request served
+	at com.acme.Worker.run(Worker.java:10)
+	at java.lang.Thread.run(Thread.java:750)
+2022-04-17 11:25:13 WARN [worker-2] slow request
+Caused by: java.io.IOException: closed
+	at com.acme.Stream.read(Stream.java:5)
+	... 2 more
+2022-04-17 11:25:14 INFO [worker-1] next request
+`
+	lines, state := parseLines(testing, data, ParseOptions{})
+	if len(lines) != 4 {
+		testing.Fatalf("Expected 4 lines, got %d: %+v", len(lines), lines)
+	}
+	if lines[0].Num != 1 || len(lines[0].Stack) != 6 || lines[0].Stack[3] != "Caused by: java.io.IOException: closed" {
+		testing.Errorf("Stack lines not stitched to their thread: %+v", lines[0])
+	}
+	if lines[1].Num != 2 || len(lines[1].Stack) != 0 {
+		testing.Errorf("Other thread should not get the stack: %+v", lines[1])
+	}
+	if len(state.Pending) != 2 || state.Pending[0].Num != 3 {
+		testing.Errorf("Open lines of both threads should be kept to resume: %+v", state.Pending)
+	}
+}
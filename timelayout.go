@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeLayout is a site's own timestamp format, written either in Go
+// reference-time syntax (02.01.2006 15:04:05) or, if it contains a '%',
+// in strftime syntax (%d.%m.%Y %H:%M:%S). Tokens is how many
+// space-separated tokens of a line the timestamp takes up and Sample is a
+// line it must parse.
+type TimeLayout struct {
+	Layout string `json:"layout"`
+	Tokens int    `json:"tokens"`
+	Sample string `json:"sample"`
+}
+
+var strftimeDirectives = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'e': "_2",
+	'j': "002",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'p': "PM",
+	'b': "Jan",
+	'h': "Jan",
+	'B': "January",
+	'a': "Mon",
+	'A': "Monday",
+	'z': "-0700",
+	'Z': "MST",
+	'F': "2006-01-02",
+	'T': "15:04:05",
+	'D': "01/02/06",
+	'%': "%",
+}
+
+func (l TimeLayout) goLayout() (string, error) {
+	if !strings.Contains(l.Layout, "%") {
+		return l.Layout, nil
+	}
+	var layout strings.Builder
+	for i := 0; i < len(l.Layout); i++ {
+		c := l.Layout[i]
+		if c != '%' {
+			layout.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(l.Layout) {
+			return "", fmt.Errorf("layout %s ends with %%", l.Layout)
+		}
+		directive, ok := strftimeDirectives[l.Layout[i]]
+		if !ok {
+			return "", fmt.Errorf("layout %s has unsupported directive %%%c", l.Layout, l.Layout[i])
+		}
+		layout.WriteString(directive)
+	}
+	return layout.String(), nil
+}
+
+func (l TimeLayout) timeFormat() timeFormat {
+	layout, err := l.goLayout()
+	if err != nil {
+		layout = l.Layout
+	}
+	tokens := l.Tokens
+	if tokens <= 0 {
+		tokens = len(strings.Fields(layout))
+	}
+	return timeFormat{format: layout, tokenCount: tokens}
+}
+
+// validate checks the layout converts and reads a timestamp from the start
+// of its sample line.
+func (l TimeLayout) validate() error {
+	if _, err := l.goLayout(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(l.Sample) == "" {
+		return fmt.Errorf("time layout %s needs a sample line", l.Layout)
+	}
+	tp := timeParser{loc: time.UTC, formats: []timeFormat{l.timeFormat()}}
+	tokens := append(splitRx.Split(strings.TrimSpace(l.Sample), -1), "")
+	if t, _, _ := tp.popFormats(tp.formats, tokens); t == nil {
+		return fmt.Errorf("time layout %s does not match sample %s", l.Layout, l.Sample)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeLayouts(testing *testing.T) {
+	layouts := []TimeLayout{
+		{Layout: "02.01.2006 15:04:05", Tokens: 2, Sample: "17.04.2022 11:25:12 Vendor format"},
+		{Layout: "%Y/%m/%d-%H:%M:%S", Sample: "2022/04/17-11:25:12.345 Another vendor"},
+	}
+	for _, layout := range layouts {
+		if err := layout.validate(); err != nil {
+			testing.Errorf("Layout should validate: %v", err)
+		}
+	}
+
+	lines, _ := parseLines(testing, "17.04.2022 11:25:12 INFO first\n2022/04/17-11:25:12.345 WARN second\n", ParseOptions{TimeLayouts: layouts})
+	std := time.Date(2022, 04, 17, 11, 25, 12, 0, time.UTC)
+	if len(lines) != 2 || lines[0].On == nil || !lines[0].On.Equal(std) || lines[0].Msg != "first" {
+		testing.Fatalf("Go layout not used: %+v", lines)
+	}
+	if lines[1].On == nil || !lines[1].On.Equal(std.Add(345*time.Millisecond)) || lines[1].Msg != "second" {
+		testing.Errorf("strftime layout not used: %+v", lines[1])
+	}
+
+	bad := []TimeLayout{
+		{Layout: "02.01.2006 15:04:05", Tokens: 2},
+		{Layout: "02.01.2006 15:04:05", Tokens: 2, Sample: "2022-04-17 11:25:12 wrong"},
+		{Layout: "%Q", Sample: "anything"},
+	}
+	for _, layout := range bad {
+		if err := layout.validate(); err == nil {
+			testing.Errorf("Layout should not validate: %+v", layout)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// onEntry is true for rules that run on parsed entries rather than on raw
+// lines: those with a Field, those that act on what only an entry has, and
+// drop and keep-only so that they keep or drop whole multi-line entries.
+func (tr *CompiledTransformer) onEntry() bool {
+	switch tr.Action {
+	case "set-level", "add-tag", "extract", "drop", "keep-only":
+		return true
+	}
+	return tr.Field != ""
+}
+
+// matches is true if the rule applies to the text: the file name, Match
+// and Find all match, where given.
+func (tr *CompiledTransformer) matches(name string, text string) bool {
+	if tr.FileNames != nil && tr.FileNames.FindStringIndex(name) == nil {
+		return false
+	}
+	if tr.Match != nil && tr.Match.FindStringIndex(text) == nil {
+		return false
+	}
+	return tr.Find == nil || tr.Find.FindStringIndex(text) != nil
+}
+
+// mask replaces what Find (or Match) matches with Value, or with as many
+// stars if there is no Value.
+func (tr *CompiledTransformer) mask(text string) string {
+	rx := tr.Find
+	if rx == nil {
+		rx = tr.Match
+	}
+	if rx == nil {
+		return text
+	}
+	if tr.Find != nil && tr.Match != nil && tr.Match.FindStringIndex(text) == nil {
+		return text
+	}
+	return rx.ReplaceAllStringFunc(text, func(s string) string {
+		if tr.Value != "" {
+			return tr.Value
+		}
+		return strings.Repeat("*", len(s))
+	})
+}
+
+func (tr *CompiledTransformer) extractRx() *regexp.Regexp {
+	if tr.Find != nil {
+		return tr.Find
+	}
+	return tr.Match
+}
+
+// joinSeparator is what goes between a line and the next one it is
+// joined with: the Value of the first join rule, or a space.
+func joinSeparator(trs []*CompiledTransformer, name string, line string) string {
+	for _, tr := range trs {
+		if tr.Action == "join-with-next" && tr.matches(name, line) && tr.Value != "" {
+			return tr.Value
+		}
+	}
+	return " "
+}
+
+// transformEntry applies the rules that run on parsed entries and returns
+// whether to keep the entry.
+func transformEntry(trs []*CompiledTransformer, name string, ll *LogLine) bool {
+	for _, tr := range trs {
+		if !tr.onEntry() {
+			continue
+		}
+		if !applyEntryTransformer(tr, name, ll) {
+			return false
+		}
+	}
+	return true
+}
+
+func applyEntryTransformer(tr *CompiledTransformer, name string, ll *LogLine) bool {
+	field := tr.Field
+	if field == "" {
+		field = "raw"
+	}
+	if tr.FileNames != nil && tr.FileNames.FindStringIndex(name) == nil {
+		return true
+	}
+	text, ok := entryField(ll, field)
+	if tr.Action == "keep-only" {
+		return ok && tr.matches(name, text)
+	}
+	if !ok || !tr.matches(name, text) {
+		return true
+	}
+
+	switch tr.Action {
+	case "drop":
+		return false
+	case "set-level":
+		level := tr.Value
+		ll.Level = &level
+	case "add-tag":
+		if !contains(ll.Tags, tr.Value) {
+			ll.Tags = append(ll.Tags, tr.Value)
+		}
+	case "extract":
+		rx := tr.extractRx()
+		m := rx.FindStringSubmatch(text)
+		for i, group := range rx.SubexpNames() {
+			if group == "" || m == nil {
+				continue
+			}
+			if ll.Fields == nil {
+				ll.Fields = map[string]string{}
+			}
+			ll.Fields[group] = m[i]
+		}
+	case "mask":
+		setEntryField(ll, field, tr.mask(text))
+	default:
+		text, keep := applyTransformer(tr, name, text)
+		if !keep {
+			return false
+		}
+		setEntryField(ll, field, text)
+	}
+	return true
+}
+
+// entryField is the text of a field of the entry: msg, level, src, raw or
+// one of its Fields.
+func entryField(ll *LogLine, field string) (string, bool) {
+	switch field {
+	case "msg":
+		return ll.Msg, true
+	case "raw":
+		return ll.Raw, true
+	case "level":
+		if ll.Level == nil {
+			return "", false
+		}
+		return *ll.Level, true
+	case "src":
+		if ll.Src == nil {
+			return "", false
+		}
+		return *ll.Src, true
+	}
+	value, ok := ll.Fields[field]
+	return value, ok
+}
+
+func setEntryField(ll *LogLine, field string, text string) {
+	switch field {
+	case "msg":
+		ll.Msg = text
+	case "raw":
+		ll.Raw = text
+	case "level":
+		ll.Level = &text
+	case "src":
+		ll.Src = &text
+	default:
+		ll.Fields[field] = text
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransformerActions(testing *testing.T) {
+	data := `2022-04-17 11:25:12 INFO [main] login user=bob card=4111111111111111
+2022-04-17 11:25:13 DEBUG [main] heartbeat
+2022-04-17 11:25:14 INFO [worker-1] order 42 placed \
+  for 12 items
+2022-04-17 11:25:15 NOTICE [audit] checked
+2022-04-17 11:25:16 INFO [main] skip me
+`
+	opts := ParseOptions{Transformers: []LogTransform{
+		{Action: "drop", Match: "heartbeat"},
+		{Action: "mask", Find: `\d{16}`},
+		{Action: "join-with-next", Match: `\\$`, Value: ""},
+		{Action: "replace", Find: `\s*\\$`, Replace: ""},
+		{Action: "extract", Find: `user=(?P<user>\w+)`},
+		{Action: "set-level", Field: "level", Match: "^NOTICE$", Value: "INFO"},
+		{Action: "add-tag", Field: "src", Match: "audit", Value: "audit"},
+		{Action: "drop", Field: "msg", Match: "^skip"},
+		{Action: "keep-only", FileNames: "other.log", Match: "nothing"},
+	}}
+	lines, _ := parseLines(testing, data, opts)
+	if len(lines) != 3 {
+		testing.Fatalf("Expected 3 lines, got %d: %+v", len(lines), lines)
+	}
+	if !strings.HasSuffix(lines[0].Msg, "card=****************") || lines[0].Fields["user"] != "bob" {
+		testing.Errorf("Mask or extract not applied: %+v", lines[0])
+	}
+	if lines[1].Msg != "order 42 placed   for 12 items" || lines[1].StartLine != 3 || lines[1].EndLine != 4 {
+		testing.Errorf("Lines not joined: %+v", lines[1])
+	}
+	if *lines[2].Level != "INFO" || lines[2].Severity != SeverityInfo || len(lines[2].Tags) != 1 {
+		testing.Errorf("Level or tag not set: %+v", lines[2])
+	}
+
+	lines, _ = parseLines(testing, data, ParseOptions{Transformers: []LogTransform{{Action: "keep-only", Match: "INFO"}}})
+	if len(lines) != 3 {
+		testing.Errorf("Keep-only should drop the other lines: %+v", lines)
+	}
+
+	stack := "2022-04-17 11:25:12 ERROR failed\njava.lang.NullPointerException\n\tat com.acme.Api.call(Api.java:10)\n2022-04-17 11:25:13 INFO fine\n"
+	lines, _ = parseLines(testing, stack, ParseOptions{Transformers: []LogTransform{{Action: "keep-only", Match: "ERROR"}}})
+	if len(lines) != 1 || !strings.HasSuffix(lines[0].Msg, "NullPointerException") || len(lines[0].Stack) != 1 {
+		testing.Errorf("Keep-only should keep whole entries: %+v", lines)
+	}
+
+	for _, bad := range []LogTransform{{Action: "explode"}, {Action: "set-level"}, {Action: "extract", Find: "no groups"}, {Action: "join-with-next", Field: "msg"}} {
+		if _, err := compile(bad); err == nil {
+			testing.Errorf("Rule should not compile: %+v", bad)
+		}
+	}
+}
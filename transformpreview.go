@@ -0,0 +1,121 @@
+package main
+
+import "errors"
+
+// TransformerPreview is what one transformer rule does to the sample
+// lines, after the rules before it have been applied.
+type TransformerPreview struct {
+	Rule    int               `json:"rule"`
+	Error   string            `json:"error"`
+	Matched []TransformedLine `json:"matched"`
+	Dropped int               `json:"dropped"`
+}
+
+// TransformedLine is a sample line a rule matched, by its index in the
+// sample. For rules on entries the line is parsed on its own, Before and
+// After are the field's text and Entry is the entry after the rule.
+type TransformedLine struct {
+	Line    int      `json:"line"`
+	Before  string   `json:"before"`
+	After   string   `json:"after"`
+	Dropped bool     `json:"dropped"`
+	Entry   *LogLine `json:"entry"`
+}
+
+// PreviewTransformers dry-runs the site's transformers over some sample
+// lines so a rule can be checked before it is saved. File name filters
+// are not applied as the lines come from no file.
+func (a *App) PreviewTransformers(config FTPConfig, sampleLines []string) []TransformerPreview {
+	return previewTransformers(config.Transformers, sampleLines)
+}
+
+func previewTransformers(transformers []LogTransform, sampleLines []string) []TransformerPreview {
+	lines := append([]string(nil), sampleLines...)
+	kept := make([]bool, len(lines))
+	for i := range kept {
+		kept[i] = true
+	}
+
+	previews := []TransformerPreview{}
+	for rule, transformer := range transformers {
+		preview := TransformerPreview{Rule: rule, Matched: []TransformedLine{}}
+		tr, err := compile(transformer)
+		if err != nil {
+			var te *TransformerError
+			if errors.As(err, &te) {
+				te.Rule = rule
+			}
+			preview.Error = err.Error()
+			previews = append(previews, preview)
+			continue
+		}
+		tr.FileNames = nil
+
+		for i, line := range lines {
+			if !kept[i] {
+				continue
+			}
+			if tr.onEntry() {
+				if matched, ok := previewEntry(tr, i, line); ok {
+					preview.Matched = append(preview.Matched, matched)
+					if matched.Dropped {
+						preview.Dropped++
+						kept[i] = false
+					}
+				}
+				continue
+			}
+			if tr.Action == "join-with-next" {
+				if !tr.matches("", line) {
+					continue
+				}
+				after := line
+				for j := i + 1; j < len(lines); j++ {
+					if kept[j] {
+						after = line + joinSeparator([]*CompiledTransformer{tr}, "", line) + lines[j]
+						kept[j] = false
+						break
+					}
+				}
+				preview.Matched = append(preview.Matched, TransformedLine{Line: i, Before: line, After: after})
+				lines[i] = after
+				continue
+			}
+			after, keep := applyTransformer(tr, "", line)
+			if keep && after == line && !tr.matches("", line) {
+				continue
+			}
+			preview.Matched = append(preview.Matched, TransformedLine{Line: i, Before: line, After: after, Dropped: !keep})
+			if !keep {
+				preview.Dropped++
+				kept[i] = false
+			}
+			lines[i] = after
+		}
+		previews = append(previews, preview)
+	}
+	return previews
+}
+
+func previewEntry(tr *CompiledTransformer, i int, line string) (TransformedLine, bool) {
+	// blank lines are not entries when parsing
+	if line == "" {
+		return TransformedLine{}, false
+	}
+	ll := parseLogLine(line, ParseOptions{}.timeParser(), newLevelMatcher(nil))
+	if ll.Msg == "" {
+		ll.Msg = ll.Raw
+	}
+	field := tr.Field
+	if field == "" {
+		field = "raw"
+	}
+	before, ok := entryField(&ll, field)
+	matched := ok && tr.matches("", before)
+	keep := applyEntryTransformer(tr, "", &ll)
+	if !matched && keep {
+		return TransformedLine{}, false
+	}
+	after, _ := entryField(&ll, field)
+	return TransformedLine{Line: i, Before: before, After: after, Dropped: !keep, Entry: &ll}, true
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreviewTransformers(testing *testing.T) {
+	sample := []string{
+		"2022-04-17 11:25:12 INFO user=bob password=secret",
+		"2022-04-17 11:25:13 DEBUG heartbeat",
+		"2022-04-17 11:25:14 INFO done",
+	}
+	previews := previewTransformers([]LogTransform{
+		{Find: `password=\S+`, Replace: "password=***"},
+		{Match: "heartbeat"},
+		{Match: "("},
+		{Match: "INFO", Find: "INFO", Replace: "NOTICE"},
+	}, sample)
+
+	if len(previews) != 4 {
+		testing.Fatalf("Expected a preview per rule: %+v", previews)
+	}
+	if len(previews[0].Matched) != 1 || previews[0].Matched[0].After != "2022-04-17 11:25:12 INFO user=bob password=***" {
+		testing.Errorf("Bad replace preview: %+v", previews[0])
+	}
+	if previews[1].Dropped != 1 || !previews[1].Matched[0].Dropped || previews[1].Matched[0].Line != 1 {
+		testing.Errorf("Bad drop preview: %+v", previews[1])
+	}
+	if !strings.HasPrefix(previews[2].Error, "transformer 2:") || len(previews[2].Matched) != 0 {
+		testing.Errorf("Compile error not reported: %+v", previews[2])
+	}
+	if len(previews[3].Matched) != 2 || previews[3].Matched[0].Before != previews[0].Matched[0].After || previews[3].Matched[1].Line != 2 {
+		testing.Errorf("Rules should apply in order: %+v", previews[3])
+	}
+	previews = previewTransformers([]LogTransform{
+		{Action: "keep-only", Match: "INFO"},
+		{Action: "set-level", Field: "msg", Match: "done", Value: "WARN"},
+	}, sample)
+	if previews[0].Dropped != 1 || previews[0].Matched[1].Line != 1 || !previews[0].Matched[1].Dropped {
+		testing.Errorf("Keep-only should report the lines it drops: %+v", previews[0])
+	}
+	if len(previews[1].Matched) != 1 || *previews[1].Matched[0].Entry.Level != "WARN" {
+		testing.Errorf("Entry rule not previewed: %+v", previews[1])
+	}
+	previews = previewTransformers([]LogTransform{
+		{Match: "heartbeat", Find: ".*", Replace: ""},
+		{Action: "add-tag", Value: "seen"},
+	}, append(sample, ""))
+	if len(previews[1].Matched) != 2 || previews[1].Matched[1].Line != 2 {
+		testing.Errorf("Blank lines should be skipped by entry rules: %+v", previews[1])
+	}
+	if sample[0] != "2022-04-17 11:25:12 INFO user=bob password=secret" {
+		testing.Error("Sample lines should not be changed")
+	}
+}